@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"chatgpt_go/internal/gateway"
+)
+
+func runCall(args []string) int {
+	fs := newCommandFlags("call")
+	gwFlags := addGatewayFlags(fs)
+	body := fs.String("body", "", "Raw request body, or @file to read it from a file")
+	contentType := fs.String("content-type", "", "Request body content type (default from the endpoint definition)")
+	include := fs.Bool("i", false, "Print the response status line and headers")
+	verbose := fs.Bool("v", false, "Log gateway activity to stderr")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "call: operationId is required")
+		fs.Usage()
+		return 2
+	}
+
+	var opts []gateway.Option
+	if *verbose {
+		opts = append(opts, gateway.WithLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+	}
+	gw, err := gwFlags.load(opts...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "call: %v\n", err)
+		return 2
+	}
+	operationID := fs.Arg(0)
+	_, endpoint, ok := gw.Operation(operationID)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "call: unknown operation %q\n", operationID)
+		return 2
+	}
+	in, err := buildCallRequest(endpoint, operationID, fs.Args()[1:], *body)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "call: %v\n", err)
+		return 2
+	}
+	in.ContentType = *contentType
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	result, err := gw.Invoke(ctx, in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "call: %v\n", err)
+		return 2
+	}
+
+	fmt.Fprintf(os.Stderr, "%s %s -> %d (%.1fms, request %s)\n", result.Method, result.URL, result.Status, result.DurationMs, result.RequestID)
+	if *include {
+		fmt.Printf("HTTP %d\n", result.Status)
+		names := make([]string, 0, len(result.Headers))
+		for name := range result.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, value := range result.Headers[name] {
+				fmt.Printf("%s: %s\n", name, value)
+			}
+		}
+		fmt.Println()
+	}
+	fmt.Println(formatCallBody(result.Body))
+	if result.Status >= 400 {
+		return 1
+	}
+	return 0
+}
+
+// buildCallRequest turns command line arguments into an invoke request.
+// key=value pairs fill declared parameters; any other key becomes a field of
+// a JSON body when the endpoint takes one, and a query parameter otherwise.
+// A bare JSON argument is used as the body.
+func buildCallRequest(ep gateway.Endpoint, operationID string, args []string, rawBody string) (gateway.InvokeRequest, error) {
+	in := gateway.InvokeRequest{OperationID: operationID, Params: make(map[string]string)}
+	declared := make(map[string]bool, len(ep.Parameters))
+	for _, p := range ep.Parameters {
+		declared[p.Name] = true
+	}
+
+	var doc any
+	fields := make(map[string]any)
+	for _, arg := range args {
+		if trimmed := strings.TrimSpace(arg); strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			if doc != nil {
+				return in, errors.New("only one JSON body argument is allowed")
+			}
+			if err := json.Unmarshal([]byte(trimmed), &doc); err != nil {
+				return in, fmt.Errorf("invalid JSON body: %w", err)
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || key == "" {
+			return in, fmt.Errorf("argument %q must be key=value or a JSON document", arg)
+		}
+		switch {
+		case declared[key]:
+			in.Params[key] = value
+		case ep.RequestBody != nil:
+			var parsed any
+			if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+				parsed = value
+			}
+			fields[key] = parsed
+		default:
+			in.Params[key] = value
+		}
+	}
+
+	if len(fields) > 0 {
+		if doc == nil {
+			doc = map[string]any{}
+		}
+		obj, ok := doc.(map[string]any)
+		if !ok {
+			return in, errors.New("key=value body fields can only be combined with a JSON object body")
+		}
+		for key, value := range fields {
+			obj[key] = value
+		}
+	}
+
+	switch {
+	case rawBody != "" && doc != nil:
+		return in, errors.New("use either --body or JSON/key=value body arguments, not both")
+	case strings.HasPrefix(rawBody, "@"):
+		data, err := os.ReadFile(rawBody[1:])
+		if err != nil {
+			return in, err
+		}
+		in.Body = string(data)
+	case rawBody != "":
+		in.Body = rawBody
+	case doc != nil:
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return in, err
+		}
+		in.Body = string(data)
+	}
+	return in, nil
+}
+
+func formatCallBody(body string) string {
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(body), "", "  "); err == nil {
+		return strings.TrimRight(out.String(), "\n")
+	}
+	return strings.TrimRight(body, "\n")
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+
+	"chatgpt_go/internal/gateway"
+)
+
+type command struct {
+	usage string
+	run   func(args []string) int
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"validate": {usage: "validate [--strict] [dir|file...]", run: runValidate},
+		"lint":     {usage: "lint [--config dir] [--base-url url] [--strict]", run: runLint},
+		"export":   {usage: "export --base-url url [--config dir] [--format json|yaml] [-o file]", run: runExport},
+		"init":     {usage: "init --name name --address url [--sample \"METHOD /path\"...] [--config dir] [-o file] [--force]", run: runInit},
+		"diff":     {usage: "diff [--gateway-config file] [--state-file file] [--strict] old new (config directories or OpenAPI files)", run: runDiff},
+		"call":     {usage: "call [--config dir] [--body json|@file] [-i] [-v] operationId [key=value...] [json]", run: runCall},
+	}
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage:\n  %s [flags]\n", os.Args[0])
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %s %s\n", os.Args[0], commands[name].usage)
+	}
+	fmt.Fprintln(out, "\nFlags:")
+	flag.PrintDefaults()
+}
+
+func newCommandFlags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s %s\n", os.Args[0], commands[name].usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+type gatewayFlags struct {
+	fs         *flag.FlagSet
+	configFile string
+	cfg        gateway.ServerConfig
+}
+
+// addGatewayFlags adds the flags subcommands share with the server. Their
+// values are layered over gateway.yaml and the environment in load.
+func addGatewayFlags(fs *flag.FlagSet) *gatewayFlags {
+	f := &gatewayFlags{
+		fs:         fs,
+		configFile: envOrDefault("CHATGPT_GATEWAY_CONFIG_FILE", defaultServerConfigFile),
+		cfg:        gateway.DefaultServerConfig(),
+	}
+	fs.StringVar(&f.configFile, "gateway-config", f.configFile, "Server configuration file")
+	fs.StringVar(&f.cfg.ServicesDir, "config", f.cfg.ServicesDir, "Directory containing MCP server definitions")
+	fs.StringVar(&f.cfg.StateFile, "state-file", f.cfg.StateFile, "JSON file with runtime enable/disable overrides")
+	return f
+}
+
+// resolve returns the server configuration with the flags applied.
+func (f *gatewayFlags) resolve() (gateway.ServerConfig, error) {
+	return newServerConfigSource(f.configFile, f.fs).resolve()
+}
+
+// load builds a gateway from the config directory without starting any
+// listener or watcher. Files that fail to load are reported on stderr.
+func (f *gatewayFlags) load(opts ...gateway.Option) (*gateway.Gateway, error) {
+	cfg, err := f.resolve()
+	if err != nil {
+		return nil, err
+	}
+	return loadGateway(cfg, opts...)
+}
+
+func loadGateway(cfg gateway.ServerConfig, opts ...gateway.Option) (*gateway.Gateway, error) {
+	if info, err := os.Stat(cfg.ServicesDir); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", cfg.ServicesDir)
+	}
+	opts = append([]gateway.Option{
+		gateway.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		gateway.WithServerConfig(cfg),
+		gateway.WithStateFile(cfg.StateFile),
+		gateway.WithReadOnlyState(),
+	}, opts...)
+	gw, err := gateway.New(cfg.ServicesDir, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := gw.LoadExisting(); err != nil {
+		return nil, err
+	}
+	for _, file := range gw.FileStatuses() {
+		if file.Status != "ok" {
+			fmt.Fprintf(os.Stderr, "skipping %s\n", file.Error)
+		}
+	}
+	return gw, nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"chatgpt_go/internal/gateway"
+	"gopkg.in/yaml.v3"
+)
+
+// diffBaseURL is the server URL given to config directories when neither
+// side is an exported file, so the server entry never shows up as a change.
+const diffBaseURL = "https://gateway.invalid"
+
+func runDiff(args []string) int {
+	fs := newCommandFlags("diff")
+	gwFlags := addGatewayFlags(fs)
+	strict := fs.Bool("strict", false, "Exit with 1 on any change, not only breaking ones")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "diff: expected exactly two config directories or OpenAPI files")
+		fs.Usage()
+		return 2
+	}
+	// Overrides from the state file and settings from gateway.yaml apply to
+	// both config directories.
+	cfg, err := gwFlags.resolve()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "diff: %v\n", err)
+		return 2
+	}
+	// Files are read first so directories can be built with the same server
+	// URL as the file they are compared with.
+	var specs [2][]byte
+	baseURL := diffBaseURL
+	for i, path := range fs.Args() {
+		if info, err := os.Stat(path); err != nil {
+			fmt.Fprintf(os.Stderr, "diff: %v\n", err)
+			return 2
+		} else if info.IsDir() {
+			continue
+		}
+		spec, err := readSpecFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "diff: %v\n", err)
+			return 2
+		}
+		specs[i] = spec
+		if url := specServerURL(spec); url != "" {
+			baseURL = url
+		}
+	}
+	for i, path := range fs.Args() {
+		if specs[i] != nil {
+			continue
+		}
+		dirCfg := cfg
+		dirCfg.ServicesDir = path
+		gw, err := loadGateway(dirCfg)
+		if err == nil {
+			specs[i], err = gw.BuildOpenAPISpec(baseURL)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "diff: %v\n", err)
+			return 2
+		}
+	}
+	changes, err := gateway.DiffOpenAPISpecs(specs[0], specs[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "diff: %v\n", err)
+		return 2
+	}
+
+	breaking := 0
+	for _, c := range changes {
+		kind := "compatible"
+		if c.Breaking {
+			kind = "breaking"
+			breaking++
+		}
+		if c.Method != "" {
+			fmt.Printf("%s: %s %s (%s): %s\n", kind, c.Method, c.Path, c.OperationID, c.Message)
+		} else {
+			fmt.Printf("%s: %s\n", kind, c.Message)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%d breaking, %d compatible change(s)\n", breaking, len(changes)-breaking)
+	if breaking > 0 || (*strict && len(changes) > 0) {
+		return 1
+	}
+	return 0
+}
+
+func readSpecFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if ext := strings.ToLower(filepath.Ext(path)); ext != ".yaml" && ext != ".yml" {
+		return data, nil
+	}
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return json.Marshal(doc)
+}
+
+func specServerURL(spec []byte) string {
+	var doc struct {
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+	}
+	if json.Unmarshal(spec, &doc) != nil || len(doc.Servers) == 0 {
+		return ""
+	}
+	return doc.Servers[0].URL
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"chatgpt_go/internal/gateway"
+)
+
+func runExport(args []string) int {
+	fs := newCommandFlags("export")
+	gwFlags := addGatewayFlags(fs)
+	baseURL := fs.String("base-url", "", "Public URL ChatGPT will use to reach the gateway (default openapi.serverUrl from gateway.yaml)")
+	format := fs.String("format", "", "Output format: json or yaml (default from the -o extension, otherwise json)")
+	version := fs.String("openapi-version", "", "OpenAPI version to emit: 3.1 or 3.0 (default openapi.specVersion from gateway.yaml)")
+	output := fs.String("o", "-", "Output file, or - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *format == "" {
+		*format = "json"
+		if ext := strings.ToLower(filepath.Ext(*output)); ext == ".yaml" || ext == ".yml" {
+			*format = "yaml"
+		}
+	}
+	if *format != "json" && *format != "yaml" {
+		fmt.Fprintf(os.Stderr, "export: unknown format %q (expected json or yaml)\n", *format)
+		return 2
+	}
+
+	gw, err := gwFlags.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %v\n", err)
+		return 2
+	}
+	if *baseURL == "" {
+		*baseURL = gw.PublicBaseURL()
+	}
+	if *baseURL == "" {
+		fmt.Fprintln(os.Stderr, "export: --base-url is required when openapi.serverUrl is not configured")
+		fs.Usage()
+		return 2
+	}
+	if *version == "" {
+		*version = gw.SpecVersion()
+	}
+	spec, err := gw.BuildOpenAPISpec(strings.TrimRight(*baseURL, "/"))
+	if err == nil {
+		spec, err = gateway.ConvertOpenAPISpec(spec, *version)
+	}
+	if err == nil && *format == "yaml" {
+		spec, err = gateway.SpecToYAML(spec)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %v\n", err)
+		return 1
+	}
+
+	if *output == "-" {
+		_, err = os.Stdout.Write(spec)
+	} else {
+		err = os.WriteFile(*output, spec, 0o644)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %v\n", err)
+		return 1
+	}
+	return 0
+}
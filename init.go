@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"chatgpt_go/internal/gateway"
+)
+
+var serviceNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+type sampleFlags []string
+
+func (s *sampleFlags) String() string { return strings.Join(*s, ", ") }
+
+func (s *sampleFlags) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+func runInit(args []string) int {
+	fs := newCommandFlags("init")
+	gwFlags := addGatewayFlags(fs)
+	name := fs.String("name", "", "Service name (required)")
+	address := fs.String("address", "", "Base URL of the running service (required)")
+	description := fs.String("description", "", "Service description (default from the service's OpenAPI info)")
+	output := fs.String("o", "", "Output file, or - for stdout (default <config>/<name>.yaml)")
+	force := fs.Bool("force", false, "Overwrite an existing file")
+	var samples sampleFlags
+	fs.Var(&samples, "sample", "Sample request such as \"GET /items/42\" or 'POST /items {\"name\":\"x\"}' (repeatable; skips /openapi.json)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *name == "" || *address == "" {
+		fmt.Fprintln(os.Stderr, "init: --name and --address are required")
+		fs.Usage()
+		return 2
+	}
+	if !serviceNamePattern.MatchString(*name) {
+		fmt.Fprintf(os.Stderr, "init: service name %q may only contain letters, digits, '_' and '-'\n", *name)
+		return 2
+	}
+	if *output == "" {
+		cfg, err := gwFlags.resolve()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "init: %v\n", err)
+			return 2
+		}
+		*output = filepath.Join(cfg.ServicesDir, *name+".yaml")
+	}
+	if *output != "-" && !*force {
+		if _, err := os.Stat(*output); err == nil {
+			fmt.Fprintf(os.Stderr, "init: %s already exists (use --force to overwrite)\n", *output)
+			return 2
+		} else if !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "init: %v\n", err)
+			return 2
+		}
+	}
+
+	svc, notes, err := gateway.Scaffold(context.Background(), gateway.ScaffoldOptions{
+		Name:        *name,
+		Address:     *address,
+		Description: *description,
+		Samples:     samples,
+	})
+	for _, note := range notes {
+		fmt.Fprintf(os.Stderr, "note: %s\n", note)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "init: %v\n", err)
+		return 1
+	}
+	data, err := gateway.MarshalService(svc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "init: %v\n", err)
+		return 1
+	}
+
+	if *output == "-" {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = os.WriteFile(*output, data, 0o644)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "init: %v\n", err)
+		return 1
+	}
+	if *output != "-" {
+		fmt.Fprintf(os.Stderr, "wrote %s with %d endpoint(s); review descriptions and parameter schemas before publishing\n", *output, len(svc.Endpoints))
+	}
+	return 0
+}
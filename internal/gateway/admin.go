@@ -0,0 +1,294 @@
+package gateway
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+type FileStatus struct {
+	File      string    `json:"file"`
+	Path      string    `json:"path"`
+	Service   string    `json:"service,omitempty"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Line      int       `json:"line,omitempty"`
+	Column    int       `json:"column,omitempty"`
+	Stale     bool      `json:"stale,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type AdminEndpoint struct {
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	OperationID string `json:"operationId"`
+	Description string `json:"description,omitempty"`
+	Enabled     bool   `json:"enabled"`
+}
+
+type AdminService struct {
+	Name        string          `json:"name"`
+	Address     string          `json:"address"`
+	Description string          `json:"description,omitempty"`
+	Enabled     bool            `json:"enabled"`
+	Source      string          `json:"source"`
+	Endpoints   []AdminEndpoint `json:"endpoints"`
+	Health      ServiceHealth   `json:"health"`
+}
+
+type AdminRoute struct {
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	Service     string `json:"service"`
+	OperationID string `json:"operationId"`
+	Upstream    string `json:"upstream"`
+	Enabled     bool   `json:"enabled"`
+}
+
+type AdminStatus struct {
+	ConfigDir  string    `json:"configDir"`
+	StartedAt  time.Time `json:"startedAt"`
+	LastReload time.Time `json:"lastReload"`
+	Services   int       `json:"services"`
+	Routes     int       `json:"routes"`
+	FilesOK    int       `json:"filesOk"`
+	FilesError int       `json:"filesError"`
+}
+
+func (g *Gateway) recordFileStatus(path, service string, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.recordFileStatusLocked(path, service, err)
+}
+
+func (g *Gateway) recordFileStatusLocked(path, service string, err error) {
+	status := &FileStatus{
+		File:      filepath.Base(path),
+		Path:      path,
+		Service:   service,
+		Status:    "ok",
+		UpdatedAt: time.Now(),
+	}
+	if err != nil {
+		status.Status = "error"
+		status.Error = err.Error()
+		var loadErr *LoadError
+		if errors.As(err, &loadErr) {
+			status.Line, status.Column = loadErr.Line, loadErr.Column
+		}
+		if active, ok := g.fileToService[path]; ok {
+			status.Stale = true
+			if service == "" {
+				status.Service = active
+			}
+		}
+	}
+	g.files[path] = status
+	if g.openAPIInfo.IncludeLoadErrors {
+		g.invalidateSpecCacheLocked()
+	}
+	g.events.publish("config", nil)
+}
+
+func (g *Gateway) AdminServices() []AdminService {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	out := make([]AdminService, 0, len(g.services))
+	for _, svc := range g.services {
+		item := AdminService{
+			Name:        svc.Name,
+			Address:     svc.Address,
+			Description: svc.Description,
+			Enabled:     g.serviceEnabled(svc),
+			Source:      svc.Source,
+			Endpoints:   make([]AdminEndpoint, 0, len(svc.Endpoints)),
+			Health:      g.health.get(svc.Name),
+		}
+		for _, ep := range svc.Endpoints {
+			item.Endpoints = append(item.Endpoints, AdminEndpoint{
+				Method:      ep.Method,
+				Path:        ep.Path,
+				OperationID: operationIDFor(svc, ep),
+				Description: ep.Description,
+				Enabled:     g.endpointEnabled(svc, ep),
+			})
+		}
+		out = append(out, item)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func (g *Gateway) AdminRoutes() []AdminRoute {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var out []AdminRoute
+	for method, routes := range g.routes {
+		for _, rt := range routes {
+			out = append(out, AdminRoute{
+				Method:      method,
+				Path:        rt.endpoint.Path,
+				Service:     rt.service.Name,
+				OperationID: operationIDFor(rt.service, rt.endpoint),
+				Upstream:    rt.service.Address + rt.endpoint.Path,
+				Enabled:     g.endpointEnabled(rt.service, rt.endpoint),
+			})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Path == out[j].Path {
+			return out[i].Method < out[j].Method
+		}
+		return out[i].Path < out[j].Path
+	})
+	return out
+}
+
+func (g *Gateway) FileStatuses() []FileStatus {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.fileStatusesLocked(false)
+}
+
+func (g *Gateway) fileStatusesLocked(errorsOnly bool) []FileStatus {
+	out := make([]FileStatus, 0, len(g.files))
+	for _, status := range g.files {
+		if errorsOnly && status.Status == "ok" {
+			continue
+		}
+		out = append(out, *status)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].File < out[j].File })
+	return out
+}
+
+func (g *Gateway) AdminStatus() AdminStatus {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	status := AdminStatus{
+		ConfigDir:  g.configDir,
+		StartedAt:  g.startedAt,
+		LastReload: g.lastReload,
+		Services:   len(g.services),
+	}
+	for _, routes := range g.routes {
+		status.Routes += len(routes)
+	}
+	for _, file := range g.files {
+		if file.Status == "ok" {
+			status.FilesOK++
+		} else {
+			status.FilesError++
+		}
+	}
+	return status
+}
+
+func (g *Gateway) AdminHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, g.AdminStatus())
+	})
+	mux.HandleFunc("/admin/services", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"services": g.AdminServices()})
+	})
+	mux.HandleFunc("/admin/services/", g.handleAdminToggle)
+	mux.HandleFunc("/admin/routes", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"routes": g.AdminRoutes()})
+	})
+	mux.HandleFunc("/admin/files", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"files": g.FileStatuses()})
+	})
+	mux.HandleFunc("/admin/requests", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"requests": g.RecentRequests()})
+	})
+	mux.HandleFunc("/admin/openapi.json", g.adminOpenAPI)
+	mux.HandleFunc("/admin/lint", g.adminLint)
+	mux.HandleFunc("/admin/diff", g.adminDiff)
+	mux.HandleFunc("/admin/events", g.adminEvents)
+	mux.HandleFunc("/admin/invoke", g.adminInvoke)
+
+	root := http.NewServeMux()
+	root.Handle("/admin/", requireAdminToken(token, mux))
+	root.Handle("/", dashboardHandler())
+	return root
+}
+
+func (g *Gateway) handleAdminToggle(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/services/"), "/"), "/")
+	var enabled *bool
+	switch parts[len(parts)-1] {
+	case "enable":
+		enabled = new(bool)
+		*enabled = true
+	case "disable":
+		enabled = new(bool)
+	case "reset":
+	default:
+		writeError(w, r, http.StatusNotFound, "unknown admin operation")
+		return
+	}
+
+	var err error
+	switch {
+	case len(parts) == 2 && parts[0] != "":
+		err = g.SetServiceEnabled(parts[0], enabled)
+	case len(parts) == 4 && parts[1] == "endpoints" && parts[0] != "" && parts[2] != "":
+		err = g.SetEndpointEnabled(parts[0], parts[2], enabled)
+	default:
+		writeError(w, r, http.StatusNotFound, "unknown admin operation")
+		return
+	}
+	if err != nil {
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			writeError(w, r, statusErr.Code, statusErr.Message)
+			return
+		}
+		g.loggerFor(r.Context()).Error("failed to save gateway state", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "failed to save gateway state")
+		return
+	}
+	for _, svc := range g.AdminServices() {
+		if svc.Name == parts[0] {
+			writeJSON(w, http.StatusOK, svc)
+			return
+		}
+	}
+	writeError(w, r, http.StatusNotFound, fmt.Sprintf("unknown service %q", parts[0]))
+}
+
+func requireAdminToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provided := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if provided == "" {
+			provided = r.URL.Query().Get("token")
+		}
+		if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="chatgpt-gateway-admin"`)
+			writeError(w, r, http.StatusUnauthorized, "admin token required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(payload)
+}
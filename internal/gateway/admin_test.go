@@ -0,0 +1,93 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileLoadStatus(t *testing.T) {
+	broken := "serviceName: broken\nserviceAddress: http://127.0.0.1:1\nendpoints:\n  - path: /broken\n    method: [GET, POST]\n"
+	cfg := DefaultServerConfig()
+	cfg.OpenAPI.IncludeLoadErrors = true
+	g := newTestGateway(t, map[string]string{
+		"items.yaml":  testService("items", "http://127.0.0.1:1"),
+		"broken.yaml": broken,
+	}, WithServerConfig(cfg))
+	admin := g.AdminHandler("admin-token")
+
+	files := func() map[string]FileStatus {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/admin/files", nil)
+		req.Header.Set("Authorization", "Bearer admin-token")
+		rec := httptest.NewRecorder()
+		admin.ServeHTTP(rec, req)
+		var body struct{ Files []FileStatus }
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("GET /admin/files got %d: %v", rec.Code, err)
+		}
+		out := make(map[string]FileStatus)
+		for _, f := range body.Files {
+			out[f.File] = f
+		}
+		return out
+	}
+	loadErrors := func() []FileStatus {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		g.OpenAPIHandler(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+		var doc struct {
+			LoadErrors []FileStatus `json:"x-gateway-load-errors"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+			t.Fatal(err)
+		}
+		return doc.LoadErrors
+	}
+
+	status := files()
+	if got := status["items.yaml"]; got.Status != "ok" || got.Service != "items" {
+		t.Fatalf("items.yaml status %+v", got)
+	}
+	if got := status["broken.yaml"]; got.Status != "error" || got.Line != 5 || got.Error == "" || got.Stale {
+		t.Fatalf("broken.yaml status %+v", got)
+	}
+	if got := g.AdminStatus(); got.FilesOK != 1 || got.FilesError != 1 || got.Services != 1 {
+		t.Fatalf("admin status %+v", got)
+	}
+	if got := loadErrors(); len(got) != 1 || got[0].File != "broken.yaml" {
+		t.Fatalf("x-gateway-load-errors %+v", got)
+	}
+
+	// A file that breaks after loading keeps its last good version.
+	path := filepath.Join(g.ConfigDir(), "items.yaml")
+	writeTestFile(t, path, strings.Replace(testService("items", "http://127.0.0.1:1"), "method: GET", "method: [GET, POST]", 1))
+	g.loadService(path)
+	if got := files()["items.yaml"]; got.Status != "error" || !got.Stale || got.Service != "items" {
+		t.Fatalf("items.yaml status after a bad edit %+v", got)
+	}
+	if rt, _ := g.matchRoute(http.MethodGet, "/items"); rt == nil {
+		t.Fatal("the last good version of items.yaml is no longer served")
+	}
+
+	writeTestFile(t, path, testService("items", "http://127.0.0.1:1"))
+	g.loadService(path)
+	g.removeService(filepath.Join(g.ConfigDir(), "broken.yaml"))
+	if got := files(); len(got) != 1 || got["items.yaml"].Status != "ok" {
+		t.Fatalf("statuses after fixing the files %+v", got)
+	}
+	if got := loadErrors(); len(got) != 0 {
+		t.Fatalf("x-gateway-load-errors after fixing the files %+v", got)
+	}
+
+	cfg.OpenAPI.IncludeLoadErrors = false
+	g.ApplyServerConfig(cfg)
+	writeTestFile(t, path, broken)
+	g.loadService(path)
+	if got := loadErrors(); len(got) != 0 {
+		t.Fatalf("load errors published although includeLoadErrors is off: %+v", got)
+	}
+}
@@ -0,0 +1,399 @@
+package gateway
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const redactedValue = "[REDACTED]"
+
+var defaultAuditRedactions = []string{"password", "secret", "token", "apiKey", "api_key", "authorization"}
+
+type AuditConfig struct {
+	Path         string
+	MaxSizeBytes int64
+	MaxAge       time.Duration
+	MaxBackups   int
+	MaxBodyBytes int
+}
+
+type AuditLogger struct {
+	cfg AuditConfig
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+type auditRecord struct {
+	Timestamp    time.Time           `json:"timestamp"`
+	RequestID    string              `json:"requestId,omitempty"`
+	Client       auditClient         `json:"client"`
+	Service      string              `json:"service"`
+	OperationID  string              `json:"operationId"`
+	Method       string              `json:"method"`
+	Path         string              `json:"path"`
+	PathParams   map[string]string   `json:"pathParams,omitempty"`
+	Query        map[string][]string `json:"query,omitempty"`
+	RequestBody  any                 `json:"requestBody,omitempty"`
+	ResponseBody any                 `json:"responseBody,omitempty"`
+	Status       int                 `json:"status"`
+	LatencyMs    float64             `json:"latencyMs"`
+}
+
+type auditClient struct {
+	RemoteAddr     string `json:"remoteAddr"`
+	ForwardedFor   string `json:"forwardedFor,omitempty"`
+	UserAgent      string `json:"userAgent,omitempty"`
+	GPTID          string `json:"gptId,omitempty"`
+	ConversationID string `json:"conversationId,omitempty"`
+	UserID         string `json:"userId,omitempty"`
+	AuthKey        string `json:"authKey,omitempty"`
+	Admin          bool   `json:"admin,omitempty"`
+}
+
+func NewAuditLogger(cfg AuditConfig) (*AuditLogger, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("audit log path is required")
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("unable to create audit log directory: %w", err)
+	}
+	a := &AuditLogger{cfg: cfg}
+	if err := a.openLocked(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func WithAuditLog(audit *AuditLogger) Option {
+	return func(g *Gateway) {
+		g.audit = audit
+	}
+}
+
+func (a *AuditLogger) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
+
+func (a *AuditLogger) write(rec *auditRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file == nil {
+		return fmt.Errorf("audit log is closed")
+	}
+	if a.needsRotationLocked(int64(len(line))) {
+		if err := a.rotateLocked(); err != nil {
+			return err
+		}
+	}
+	n, err := a.file.Write(line)
+	a.size += int64(n)
+	return err
+}
+
+func (a *AuditLogger) needsRotationLocked(next int64) bool {
+	if a.size == 0 {
+		return false
+	}
+	if a.cfg.MaxSizeBytes > 0 && a.size+next > a.cfg.MaxSizeBytes {
+		return true
+	}
+	return a.cfg.MaxAge > 0 && time.Since(a.openedAt) > a.cfg.MaxAge
+}
+
+func (a *AuditLogger) openLocked() error {
+	f, err := os.OpenFile(a.cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("unable to open audit log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	a.file = f
+	a.size = info.Size()
+	a.openedAt = time.Now()
+	if info.Size() > 0 {
+		a.openedAt = info.ModTime()
+	}
+	return nil
+}
+
+func (a *AuditLogger) rotateLocked() error {
+	if err := a.file.Close(); err != nil {
+		return err
+	}
+	ext := filepath.Ext(a.cfg.Path)
+	base := strings.TrimSuffix(a.cfg.Path, ext)
+	rotated := fmt.Sprintf("%s-%s%s", base, time.Now().UTC().Format("20060102T150405.000"), ext)
+	if err := os.Rename(a.cfg.Path, rotated); err != nil {
+		return fmt.Errorf("unable to rotate audit log: %w", err)
+	}
+	if err := a.openLocked(); err != nil {
+		return err
+	}
+	a.openedAt = time.Now()
+	a.pruneLocked(base, ext)
+	return nil
+}
+
+func (a *AuditLogger) pruneLocked(base, ext string) {
+	if a.cfg.MaxBackups <= 0 {
+		return
+	}
+	backups, err := filepath.Glob(base + "-*" + ext)
+	if err != nil || len(backups) <= a.cfg.MaxBackups {
+		return
+	}
+	sort.Strings(backups)
+	for _, old := range backups[:len(backups)-a.cfg.MaxBackups] {
+		os.Remove(old)
+	}
+}
+
+// auditBodyReader streams a request body upstream while keeping its first
+// limit bytes for the audit record.
+type auditBodyReader struct {
+	io.ReadCloser
+	info  *requestInfo
+	limit int
+}
+
+func (a *auditBodyReader) Read(p []byte) (int, error) {
+	n, err := a.ReadCloser.Read(p)
+	if remaining := a.limit - len(a.info.requestBody); remaining > 0 {
+		a.info.requestBody = append(a.info.requestBody, p[:min(n, remaining)]...)
+	}
+	return n, err
+}
+
+type captureWriter struct {
+	statusRecorder
+	info         *requestInfo
+	defaultLimit int
+	limit        int
+	body         bytes.Buffer
+}
+
+func (cw *captureWriter) Write(p []byte) (int, error) {
+	if cw.limit < 0 {
+		cw.limit = cw.defaultLimit
+		if cw.info.matched != nil {
+			cw.limit = cw.info.matched.auditRules(cw.defaultLimit).maxBodyBytes
+		}
+	}
+	if remaining := cw.limit - cw.body.Len(); remaining > 0 {
+		if len(p) < remaining {
+			remaining = len(p)
+		}
+		cw.body.Write(p[:remaining])
+	}
+	return cw.statusRecorder.Write(p)
+}
+
+func (g *Gateway) AuditMiddleware(next http.Handler) http.Handler {
+	if g.audit == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		r, info := withRequestInfo(r)
+		cw := &captureWriter{
+			statusRecorder: statusRecorder{ResponseWriter: w, status: http.StatusOK},
+			info:           info,
+			defaultLimit:   g.audit.cfg.MaxBodyBytes,
+			limit:          -1,
+		}
+		next.ServeHTTP(cw, r)
+		if info.matched == nil {
+			return
+		}
+
+		rules := info.matched.auditRules(g.audit.cfg.MaxBodyBytes)
+		client := auditClientFrom(r)
+		client.Admin = info.admin
+		rec := &auditRecord{
+			Timestamp:   start.UTC(),
+			RequestID:   info.requestID,
+			Client:      client,
+			Service:     info.service,
+			OperationID: info.operationID,
+			Method:      r.Method,
+			Path:        r.URL.Path,
+			PathParams:  rules.redactStrings(info.pathParams),
+			Query:       rules.redactQuery(r.URL.Query()),
+			Status:      cw.status,
+			LatencyMs:   float64(time.Since(start).Microseconds()) / 1000,
+		}
+		if rules.maxBodyBytes > 0 {
+			rec.RequestBody = rules.capture(info.requestBody, r.Header.Get("Content-Type"))
+			rec.ResponseBody = rules.capture(cw.body.Bytes(), cw.Header().Get("Content-Type"))
+		}
+		if err := g.audit.write(rec); err != nil {
+			g.loggerFor(r.Context()).Error("failed to write audit record", "error", err)
+		}
+	})
+}
+
+func auditClientFrom(r *http.Request) auditClient {
+	client := auditClient{
+		RemoteAddr:     r.RemoteAddr,
+		UserAgent:      r.UserAgent(),
+		GPTID:          r.Header.Get("Openai-Gpt-Id"),
+		ConversationID: r.Header.Get("Openai-Conversation-Id"),
+		UserID:         r.Header.Get("Openai-Ephemeral-User-Id"),
+		// X-Forwarded-For is whatever the caller sent, so it is kept apart
+		// from the address the connection actually came from.
+		ForwardedFor: strings.Join(r.Header.Values("X-Forwarded-For"), ", "),
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		client.RemoteAddr = host
+	}
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		sum := sha256.Sum256([]byte(auth))
+		client.AuthKey = "sha256:" + hex.EncodeToString(sum[:8])
+	}
+	return client
+}
+
+type auditPolicy struct {
+	names        map[string]bool
+	paths        map[string]bool
+	maxBodyBytes int
+}
+
+func (r *route) auditRules(maxBodyBytes int) auditPolicy {
+	policy := auditPolicy{
+		names:        make(map[string]bool),
+		paths:        make(map[string]bool),
+		maxBodyBytes: maxBodyBytes,
+	}
+	rules := append([]string{}, defaultAuditRedactions...)
+	for _, set := range []*AuditRules{r.service.Audit, r.endpoint.Audit} {
+		if set == nil {
+			continue
+		}
+		rules = append(rules, set.Redact...)
+		if set.MaxBodyBytes != nil {
+			policy.maxBodyBytes = *set.MaxBodyBytes
+		}
+	}
+	for _, rule := range rules {
+		rule = strings.ToLower(strings.TrimSpace(rule))
+		if strings.Contains(rule, ".") {
+			policy.paths[rule] = true
+		} else if rule != "" {
+			policy.names[rule] = true
+		}
+	}
+	return policy
+}
+
+func (p auditPolicy) redacts(path []string) bool {
+	if len(path) == 0 {
+		return false
+	}
+	return p.names[strings.ToLower(path[len(path)-1])] || p.paths[strings.ToLower(strings.Join(path, "."))]
+}
+
+func (p auditPolicy) redactStrings(values map[string]string) map[string]string {
+	if len(values) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(values))
+	for k, v := range values {
+		if p.redacts([]string{k}) {
+			v = redactedValue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func (p auditPolicy) redactQuery(values map[string][]string) map[string][]string {
+	if len(values) == 0 {
+		return nil
+	}
+	out := make(map[string][]string, len(values))
+	for k, v := range values {
+		if p.redacts([]string{k}) {
+			v = []string{redactedValue}
+		}
+		out[k] = v
+	}
+	return out
+}
+
+func (p auditPolicy) capture(body []byte, contentType string) any {
+	if len(body) == 0 {
+		return nil
+	}
+	if isJSONContentType(contentType) {
+		var doc any
+		if err := json.Unmarshal(body, &doc); err == nil {
+			doc = p.redactJSON(doc, nil)
+			if encoded, err := json.Marshal(doc); err == nil && len(encoded) <= p.maxBodyBytes {
+				return json.RawMessage(encoded)
+			}
+		}
+		return fmt.Sprintf("[%d bytes of JSON omitted: larger than the audit body limit]", len(body))
+	}
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "application/x-www-form-urlencoded" {
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			body = []byte(url.Values(p.redactQuery(values)).Encode())
+		}
+	}
+	if len(body) > p.maxBodyBytes {
+		body = body[:p.maxBodyBytes]
+	}
+	return string(body)
+}
+
+func (p auditPolicy) redactJSON(value any, path []string) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for k, child := range v {
+			childPath := append(path[:len(path):len(path)], k)
+			if p.redacts(childPath) {
+				v[k] = redactedValue
+				continue
+			}
+			v[k] = p.redactJSON(child, childPath)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = p.redactJSON(child, path)
+		}
+	}
+	return value
+}
@@ -0,0 +1,232 @@
+package gateway
+
+import (
+	"bytes"
+	"container/list"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultCacheMaxEntries    = 1024
+	defaultCacheMaxBytes      = 64 << 20
+	defaultCacheMaxEntryBytes = 1 << 20
+)
+
+type cacheEntry struct {
+	key      string
+	status   int
+	header   http.Header
+	body     []byte
+	etag     string
+	storedAt time.Time
+	expires  time.Time
+}
+
+func (e *cacheEntry) size() int64 {
+	return int64(len(e.body) + len(e.key))
+}
+
+type responseCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	maxBytes   int64
+	size       int64
+	ll         *list.List
+	items      map[string]*list.Element
+}
+
+func newResponseCache(maxEntries int, maxBytes int64) *responseCache {
+	return &responseCache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+func (c *responseCache) get(key string) *cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+	c.ll.MoveToFront(el)
+	return el.Value.(*cacheEntry)
+}
+
+func (c *responseCache) set(entry *cacheEntry) {
+	if c.maxEntries <= 0 || entry.size() > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[entry.key]; ok {
+		c.removeElementLocked(el)
+	}
+	c.items[entry.key] = c.ll.PushFront(entry)
+	c.size += entry.size()
+	for c.ll.Len() > c.maxEntries || c.size > c.maxBytes {
+		c.removeElementLocked(c.ll.Back())
+	}
+}
+
+func (c *responseCache) removeElementLocked(el *list.Element) {
+	entry := el.Value.(*cacheEntry)
+	c.ll.Remove(el)
+	delete(c.items, entry.key)
+	c.size -= entry.size()
+}
+
+func cacheKey(r *http.Request, rt *route, targetPath string) string {
+	return strings.Join([]string{r.Method, rt.service.Name, targetPath, r.URL.RawQuery}, "\x00")
+}
+
+func (g *Gateway) proxyCached(w http.ResponseWriter, r *http.Request, rt *route, targetPath string) error {
+	key := cacheKey(r, rt, targetPath)
+	now := time.Now()
+	entry := g.cache.get(key)
+	bypass := hasCacheDirective(r.Header, "no-cache") || hasCacheDirective(r.Header, "no-store")
+	if entry != nil && !bypass && now.Before(entry.expires) {
+		g.writeCachedResponse(w, r, entry, "HIT", now)
+		return nil
+	}
+
+	req, err := g.newUpstreamRequest(r, rt, targetPath)
+	if err != nil {
+		return err
+	}
+	// Conditional headers from the client are answered from the cache; the
+	// upstream only sees our own revalidation. Accept-Encoding is dropped so
+	// cached bodies are always stored decoded.
+	for _, h := range []string{"If-None-Match", "If-Modified-Since", "Accept-Encoding"} {
+		req.Header.Del(h)
+	}
+	if entry != nil && entry.etag != "" {
+		req.Header.Set("If-None-Match", entry.etag)
+	}
+
+	resp, err := g.doUpstream(rt, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		refreshed := *entry
+		refreshed.storedAt = now
+		refreshed.expires = now.Add(cacheTTL(rt.endpoint.Cache, resp.Header))
+		g.cache.set(&refreshed)
+		g.writeCachedResponse(w, r, &refreshed, "REVALIDATED", now)
+		return nil
+	}
+
+	maxEntry := rt.endpoint.Cache.MaxEntryBytes
+	if maxEntry == 0 {
+		maxEntry = defaultCacheMaxEntryBytes
+	}
+	limited, err := g.limitResponse(rt, resp)
+	if err != nil {
+		return err
+	}
+	body, err := io.ReadAll(io.LimitReader(limited, maxEntry+1))
+	if err != nil {
+		return err
+	}
+	ttl := cacheTTL(rt.endpoint.Cache, resp.Header)
+	etag := resp.Header.Get("ETag")
+	if int64(len(body)) <= maxEntry && isCacheableResponse(resp) && (ttl > 0 || etag != "") {
+		g.cache.set(&cacheEntry{
+			key:      key,
+			status:   resp.StatusCode,
+			header:   resp.Header.Clone(),
+			body:     body,
+			etag:     etag,
+			storedAt: now,
+			expires:  now.Add(ttl),
+		})
+	}
+
+	copyResponseHeaders(w.Header(), resp.Header)
+	w.Header().Set("X-Cache", "MISS")
+	w.WriteHeader(resp.StatusCode)
+	if _, err := io.Copy(w, io.MultiReader(bytes.NewReader(body), limited)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (g *Gateway) writeCachedResponse(w http.ResponseWriter, r *http.Request, entry *cacheEntry, status string, now time.Time) {
+	copyResponseHeaders(w.Header(), entry.header)
+	w.Header().Set("X-Cache", status)
+	w.Header().Set("Age", strconv.Itoa(int(now.Sub(entry.storedAt).Seconds())))
+	if entry.etag != "" && r.Header.Get("If-None-Match") == entry.etag {
+		w.Header().Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.WriteHeader(entry.status)
+	if _, err := w.Write(entry.body); err != nil {
+		g.loggerFor(r.Context()).Debug("failed to write cached response", "error", err)
+	}
+}
+
+func isCacheableResponse(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusOK {
+		return false
+	}
+	if resp.Header.Get("Set-Cookie") != "" {
+		return false
+	}
+	// Entries are shared by every caller, so a response that varies on
+	// request headers is not stored. Accept-Encoding is the exception:
+	// the gateway never forwards it on cached endpoints.
+	for _, line := range resp.Header.Values("Vary") {
+		for _, name := range strings.Split(line, ",") {
+			if name = strings.TrimSpace(name); name != "" && !strings.EqualFold(name, "Accept-Encoding") {
+				return false
+			}
+		}
+	}
+	return !hasCacheDirective(resp.Header, "no-store") && !hasCacheDirective(resp.Header, "private")
+}
+
+func cacheTTL(cfg *CacheConfig, header http.Header) time.Duration {
+	if hasCacheDirective(header, "no-cache") {
+		return 0
+	}
+	ttl := cfg.TTL
+	for _, name := range []string{"s-maxage", "max-age"} {
+		if value, ok := cacheDirective(header, name); ok {
+			if seconds, err := strconv.Atoi(value); err == nil {
+				if upstream := time.Duration(seconds) * time.Second; upstream < ttl {
+					ttl = upstream
+				}
+				break
+			}
+		}
+	}
+	return ttl
+}
+
+func hasCacheDirective(header http.Header, name string) bool {
+	_, ok := cacheDirective(header, name)
+	return ok
+}
+
+func cacheDirective(header http.Header, name string) (string, bool) {
+	for _, line := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(line, ",") {
+			key, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if strings.EqualFold(key, name) {
+				return strings.Trim(value, `"`), true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,91 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProxyCache(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		switch r.URL.Path {
+		case "/language":
+			w.Header().Set("Vary", "Accept-Language")
+		case "/encoding":
+			w.Header().Set("Vary", "Accept-Encoding")
+		case "/cookie":
+			w.Header().Set("Set-Cookie", "session=1")
+		case "/etag":
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Cache-Control", "no-cache")
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"path":"` + r.URL.Path + `"}`))
+	}))
+	defer upstream.Close()
+
+	g := newTestGateway(t, map[string]string{"cached.yaml": `serviceName: cached
+serviceAddress: ` + upstream.URL + `
+description: Cached endpoints.
+endpoints:
+  - {path: /plain, method: GET, operationId: plain, description: Plain., cache: {ttl: 100ms}}
+  - {path: /language, method: GET, operationId: language, description: Language., cache: {ttl: 1m}}
+  - {path: /encoding, method: GET, operationId: encoding, description: Encoding., cache: {ttl: 1m}}
+  - {path: /cookie, method: GET, operationId: cookie, description: Cookie., cache: {ttl: 1m}}
+  - {path: /etag, method: GET, operationId: etag, description: ETag., cache: {ttl: 1m}}
+`})
+
+	get := func(path string, header http.Header) string {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rec := httptest.NewRecorder()
+		g.ProxyHandler(rec, req)
+		if rec.Code != http.StatusOK || rec.Body.String() != `{"path":"`+path+`"}` {
+			t.Fatalf("GET %s: got %d %s", path, rec.Code, rec.Body)
+		}
+		return rec.Header().Get("X-Cache")
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		header   http.Header
+		sleep    time.Duration
+		want     string
+		upstream bool
+	}{
+		{name: "first request", path: "/plain", want: "MISS", upstream: true},
+		{name: "fresh entry", path: "/plain", want: "HIT"},
+		{name: "client bypass", path: "/plain", header: http.Header{"Cache-Control": {"no-cache"}}, want: "MISS", upstream: true},
+		{name: "expired entry", path: "/plain", sleep: 150 * time.Millisecond, want: "MISS", upstream: true},
+		{name: "Vary on a request header", path: "/language", header: http.Header{"Accept-Language": {"de"}}, want: "MISS", upstream: true},
+		{name: "Vary on a request header is never stored", path: "/language", header: http.Header{"Accept-Language": {"en"}}, want: "MISS", upstream: true},
+		{name: "Vary on Accept-Encoding", path: "/encoding", want: "MISS", upstream: true},
+		{name: "Vary on Accept-Encoding is stored", path: "/encoding", header: http.Header{"Accept-Encoding": {"gzip"}}, want: "HIT"},
+		{name: "Set-Cookie", path: "/cookie", want: "MISS", upstream: true},
+		{name: "Set-Cookie is never stored", path: "/cookie", want: "MISS", upstream: true},
+		{name: "ETag without freshness", path: "/etag", want: "MISS", upstream: true},
+		{name: "revalidated entry", path: "/etag", want: "REVALIDATED", upstream: true},
+	}
+	for _, tt := range tests {
+		time.Sleep(tt.sleep)
+		before := calls.Load()
+		if got := get(tt.path, tt.header); got != tt.want {
+			t.Errorf("%s: X-Cache = %q, want %q", tt.name, got, tt.want)
+		}
+		if called := calls.Load() != before; called != tt.upstream {
+			t.Errorf("%s: upstream called = %v, want %v", tt.name, called, tt.upstream)
+		}
+	}
+}
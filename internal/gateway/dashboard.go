@@ -0,0 +1,222 @@
+package gateway
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const recentRequestsSize = 200
+
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+type RecentRequest struct {
+	Time        time.Time `json:"time"`
+	RequestID   string    `json:"requestId,omitempty"`
+	Method      string    `json:"method"`
+	Path        string    `json:"path"`
+	Service     string    `json:"service,omitempty"`
+	OperationID string    `json:"operationId,omitempty"`
+	Status      int       `json:"status"`
+	LatencyMs   float64   `json:"latencyMs"`
+}
+
+type requestLog struct {
+	mu      sync.Mutex
+	entries []RecentRequest
+	next    int
+}
+
+func newRequestLog(size int) *requestLog {
+	return &requestLog{entries: make([]RecentRequest, 0, size)}
+}
+
+func (l *requestLog) add(entry RecentRequest) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.entries) < cap(l.entries) {
+		l.entries = append(l.entries, entry)
+		return
+	}
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % len(l.entries)
+}
+
+func (l *requestLog) snapshot() []RecentRequest {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]RecentRequest, 0, len(l.entries))
+	for i := range l.entries {
+		idx := (l.next + len(l.entries) - 1 - i) % len(l.entries)
+		out = append(out, l.entries[idx])
+	}
+	return out
+}
+
+type adminEvent struct {
+	kind string
+	data any
+}
+
+type eventBroker struct {
+	mu   sync.Mutex
+	subs map[chan adminEvent]struct{}
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{subs: make(map[chan adminEvent]struct{})}
+}
+
+func (b *eventBroker) subscribe() (<-chan adminEvent, func()) {
+	ch := make(chan adminEvent, 64)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subs, ch)
+		b.mu.Unlock()
+	}
+}
+
+func (b *eventBroker) publish(kind string, data any) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- adminEvent{kind: kind, data: data}:
+		default:
+		}
+	}
+}
+
+func (g *Gateway) RecentRequests() []RecentRequest {
+	return g.recent.snapshot()
+}
+
+// RecentRequestsMiddleware feeds the dashboard's list of recent requests.
+// Only requests that matched an operation are kept, so spec fetches and
+// metrics scrapes do not push action calls out of the list.
+func (g *Gateway) RecentRequestsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		r, info := withRequestInfo(r)
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		g.recordRecentRequest(r, info, rec.status, start)
+	})
+}
+
+func (g *Gateway) recordRecentRequest(r *http.Request, info *requestInfo, status int, start time.Time) {
+	if info.operationID == "" {
+		return
+	}
+	entry := RecentRequest{
+		Time:        start.UTC(),
+		RequestID:   info.requestID,
+		Method:      r.Method,
+		Path:        r.URL.Path,
+		Service:     info.service,
+		OperationID: info.operationID,
+		Status:      status,
+		LatencyMs:   float64(time.Since(start).Microseconds()) / 1000,
+	}
+	g.recent.add(entry)
+	g.events.publish("request", entry)
+}
+
+// servedBaseURL is the server URL the admin views show: the configured
+// openapi.serverUrl, or the gateway's own listen address. Public requests
+// build theirs from the Host header and never change it.
+func (g *Gateway) servedBaseURL() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.openAPIInfo.ServerURL != "" {
+		return g.openAPIInfo.ServerURL
+	}
+	host, port, err := net.SplitHostPort(g.listen.Addr)
+	if err != nil {
+		return "/"
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "localhost"
+	}
+	scheme := "http"
+	if g.listen.TLSCert != "" {
+		scheme = "https"
+	}
+	return scheme + "://" + net.JoinHostPort(host, port)
+}
+
+func (g *Gateway) adminOpenAPI(w http.ResponseWriter, r *http.Request) {
+	spec, err := g.requestedSpec(r, g.servedBaseURL())
+	if err != nil {
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			writeError(w, r, statusErr.Code, statusErr.Message)
+			return
+		}
+		g.loggerFor(r.Context()).Error("failed to build OpenAPI spec", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "failed to build OpenAPI spec")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(spec.json)
+}
+
+func (g *Gateway) adminEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	events, unsubscribe := g.events.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+	flusher.Flush()
+
+	keepalive := time.NewTicker(25 * time.Second)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		case ev := <-events:
+			data, err := json.Marshal(ev.data)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.kind, data)
+		}
+		flusher.Flush()
+	}
+}
+
+func dashboardHandler() http.Handler {
+	root, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		panic(err)
+	}
+	files := http.FileServer(http.FS(root))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Content-Security-Policy", "default-src 'self'; style-src 'self' 'unsafe-inline'")
+		files.ServeHTTP(w, r)
+	})
+}
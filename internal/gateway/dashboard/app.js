@@ -0,0 +1,408 @@
+"use strict";
+
+const maxRequestRows = 200;
+let token = "";
+let refreshTimer = null;
+let events = null;
+
+function el(tag, attrs, ...children) {
+  const node = document.createElement(tag);
+  for (const [key, value] of Object.entries(attrs || {})) {
+    if (value === undefined || value === null || value === false) continue;
+    if (key === "class") node.className = value;
+    else if (key.startsWith("on")) node.addEventListener(key.slice(2), value);
+    else node.setAttribute(key, value === true ? "" : value);
+  }
+  for (const child of children.flat()) {
+    if (child === undefined || child === null) continue;
+    node.append(child instanceof Node ? child : String(child));
+  }
+  return node;
+}
+
+async function api(path, options = {}) {
+  const resp = await fetch(path, {
+    ...options,
+    headers: { ...(options.headers || {}), Authorization: "Bearer " + token },
+  });
+  if (resp.status === 401) {
+    logout("The admin token was rejected.");
+    throw new Error("unauthorized");
+  }
+  if (!resp.ok) {
+    const body = await resp.json().catch(() => ({}));
+    throw new Error(body.error || resp.statusText);
+  }
+  return resp.json();
+}
+
+function formatTime(value) {
+  return value ? new Date(value).toLocaleTimeString() : "never";
+}
+
+function statusClass(status) {
+  return "status-" + String(status).charAt(0);
+}
+
+async function refresh() {
+  const [status, services, files, spec] = await Promise.all([
+    api("/admin/status"),
+    api("/admin/services"),
+    api("/admin/files"),
+    api("/admin/openapi.json"),
+  ]);
+  renderSummary(status);
+  renderLoadErrors(files.files);
+  renderServices(services.services);
+  renderSpec(spec);
+}
+
+function scheduleRefresh() {
+  clearTimeout(refreshTimer);
+  refreshTimer = setTimeout(() => refresh().catch(console.error), 300);
+}
+
+function renderSummary(status) {
+  const item = (label, value) => el("span", {}, label + ": ", el("strong", {}, value));
+  document.getElementById("summary").replaceChildren(
+    item("Services", status.services),
+    item("Routes", status.routes),
+    item("Files", `${status.filesOk} ok, ${status.filesError} failed`),
+    item("Last reload", formatTime(status.lastReload)),
+    item("Config", status.configDir),
+  );
+}
+
+function renderLoadErrors(files) {
+  const failed = files.filter((f) => f.status !== "ok");
+  const target = document.getElementById("load-errors");
+  if (failed.length === 0) {
+    target.replaceChildren(el("p", { class: "muted" }, "All service files loaded."));
+    return;
+  }
+  target.replaceChildren(
+    el("table", {},
+      el("thead", {}, el("tr", {}, el("th", {}, "File"), el("th", {}, "Error"), el("th", {}, "Updated"))),
+      el("tbody", {}, failed.map((f) =>
+        el("tr", {},
+          el("td", { class: "mono" }, f.file + (f.line ? ":" + f.line : "") + (f.column ? ":" + f.column : "")),
+          el("td", {}, el("div", { class: "error" }, f.error),
+            f.stale ? el("div", { class: "muted" }, `The previous definition of ${f.service} is still being served.`) : null),
+          el("td", {}, formatTime(f.updatedAt)),
+        ))),
+    ),
+  );
+}
+
+async function toggle(path) {
+  try {
+    await api(path, { method: "POST" });
+    scheduleRefresh();
+  } catch (err) {
+    alert(err.message);
+  }
+}
+
+function renderServices(services) {
+  const target = document.getElementById("services");
+  if (services.length === 0) {
+    target.replaceChildren(el("p", { class: "muted" }, "No services loaded."));
+    return;
+  }
+  target.replaceChildren(...services.map((svc) => {
+    const base = "/admin/services/" + encodeURIComponent(svc.name);
+    const health = svc.health || { state: "unknown" };
+    return el("div", { class: "card" + (svc.enabled ? "" : " disabled") },
+      el("div", { class: "card-header" },
+        el("h3", {}, svc.name),
+        el("span", { class: "badge " + health.state, title: health.lastError || "" }, health.state),
+        svc.enabled ? null : el("span", { class: "badge unknown" }, "disabled"),
+        el("span", { class: "muted mono" }, svc.address),
+        el("span", { class: "actions" },
+          el("button", { onclick: () => toggle(base + (svc.enabled ? "/disable" : "/enable")) }, svc.enabled ? "Disable" : "Enable")),
+      ),
+      svc.description ? el("p", { class: "muted" }, svc.description) : null,
+      health.lastError ? el("p", { class: "error" }, `Last error (${formatTime(health.lastFailure)}): ${health.lastError}`) : null,
+      el("table", {},
+        el("thead", {}, el("tr", {}, el("th", {}, "Method"), el("th", {}, "Path"), el("th", {}, "Operation"), el("th", {}, "Description"), el("th", {}))),
+        el("tbody", {}, svc.endpoints.map((ep) => {
+          const epBase = base + "/endpoints/" + encodeURIComponent(ep.operationId);
+          return el("tr", { class: ep.enabled ? "" : "disabled" },
+            el("td", { class: "method" }, ep.method),
+            el("td", { class: "mono" }, ep.path),
+            el("td", { class: "mono" }, ep.operationId),
+            el("td", {}, ep.description || ""),
+            el("td", {}, el("button", { onclick: () => toggle(epBase + (ep.enabled ? "/disable" : "/enable")) }, ep.enabled ? "Disable" : "Enable")),
+          );
+        })),
+      ),
+      el("p", { class: "muted" }, "Source: ", el("span", { class: "mono" }, svc.source)),
+    );
+  }));
+}
+
+function requestRow(req) {
+  return el("tr", {},
+    el("td", {}, formatTime(req.time)),
+    el("td", { class: "method" }, req.method),
+    el("td", { class: "mono" }, req.path),
+    el("td", { class: "mono" }, req.operationId || ""),
+    el("td", { class: statusClass(req.status) }, req.status),
+    el("td", {}, req.latencyMs.toFixed(1) + " ms"),
+    el("td", { class: "mono muted" }, req.requestId || ""),
+  );
+}
+
+function addRequest(req) {
+  const rows = document.getElementById("request-rows");
+  rows.prepend(requestRow(req));
+  while (rows.children.length > maxRequestRows) rows.lastChild.remove();
+}
+
+function renderSpec(spec) {
+  document.getElementById("spec-raw").textContent = JSON.stringify(spec, null, 2);
+  const info = spec.info || {};
+  document.getElementById("spec-info").replaceChildren(
+    el("p", {}, el("strong", {}, info.title || ""), " ", el("span", { class: "muted" }, "v" + (info.version || "")), " · OpenAPI ", spec.openapi),
+    info.description ? el("p", { class: "muted" }, info.description) : null,
+  );
+  const ops = [];
+  for (const [path, item] of Object.entries(spec.paths || {})) {
+    for (const [method, op] of Object.entries(item)) {
+      ops.push({ path, method, op });
+    }
+  }
+  ops.sort((a, b) => a.path.localeCompare(b.path) || a.method.localeCompare(b.method));
+  renderConsoleOperations(ops);
+  const target = document.getElementById("spec-operations");
+  if (ops.length === 0) {
+    target.replaceChildren(el("p", { class: "muted" }, "The GPT cannot see any operations."));
+    return;
+  }
+  target.replaceChildren(...ops.map(({ path, method, op }) =>
+    el("div", { class: "operation" },
+      el("div", {}, el("span", { class: "method" }, method), el("span", { class: "mono" }, path), " ", el("span", { class: "muted mono" }, op.operationId)),
+      el("div", {}, op.summary),
+      (op.parameters || []).length > 0
+        ? el("ul", {}, op.parameters.map((p) =>
+          el("li", {}, el("span", { class: "mono" }, p.name), ` (${p.in}${p.required ? ", required" : ""}${p.schema && p.schema.type ? ", " + p.schema.type : ""})`, p.description ? " – " + p.description : "")))
+        : null,
+      op.requestBody ? el("div", { class: "muted" }, "Request body: " + Object.keys(op.requestBody.content || {}).join(", ")) : null,
+    )));
+}
+
+let consoleOps = new Map();
+
+function renderConsoleOperations(ops) {
+  const select = document.getElementById("console-operation");
+  const selected = select.value;
+  consoleOps = new Map(ops.map((entry) => [entry.op.operationId, entry]));
+  select.replaceChildren(
+    el("option", { value: "" }, "Choose an operation…"),
+    ...ops.map(({ path, method, op }) =>
+      el("option", { value: op.operationId, selected: op.operationId === selected }, `${op.operationId} – ${method.toUpperCase()} ${path}`)),
+  );
+  if (selected && !consoleOps.has(selected)) renderConsoleForm("");
+}
+
+function sampleFromSchema(schema, depth = 0) {
+  if (!schema || depth > 5) return null;
+  if (schema.example !== undefined) return schema.example;
+  if (schema.default !== undefined) return schema.default;
+  if (Array.isArray(schema.enum) && schema.enum.length > 0) return schema.enum[0];
+  const type = Array.isArray(schema.type) ? schema.type.find((t) => t !== "null") : schema.type;
+  switch (type) {
+    case "object": {
+      const out = {};
+      for (const [name, prop] of Object.entries(schema.properties || {})) out[name] = sampleFromSchema(prop, depth + 1);
+      return out;
+    }
+    case "array":
+      return [sampleFromSchema(schema.items, depth + 1)].filter((v) => v !== null);
+    case "integer":
+    case "number":
+      return 0;
+    case "boolean":
+      return false;
+    case "string":
+      return "";
+    default:
+      return schema.properties ? sampleFromSchema({ ...schema, type: "object" }, depth) : null;
+  }
+}
+
+function paramInput(param) {
+  const schema = param.schema || {};
+  const attrs = { name: param.name, "data-param": param.name, required: param.required };
+  if (Array.isArray(schema.enum)) {
+    return el("select", attrs,
+      param.required ? null : el("option", { value: "" }, ""),
+      schema.enum.map((v) => el("option", { value: String(v) }, String(v))));
+  }
+  if (schema.type === "boolean") {
+    return el("select", attrs, el("option", { value: "" }, ""), el("option", { value: "true" }, "true"), el("option", { value: "false" }, "false"));
+  }
+  const type = schema.type === "integer" || schema.type === "number" ? "number" : "text";
+  const value = schema.default !== undefined ? schema.default : schema.example;
+  return el("input", { ...attrs, type, step: schema.type === "number" ? "any" : null, value: value === undefined ? null : String(value) });
+}
+
+function renderConsoleForm(operationId) {
+  const entry = consoleOps.get(operationId);
+  document.getElementById("console-result").replaceChildren();
+  const params = document.getElementById("console-params");
+  const body = document.getElementById("console-body");
+  const summary = document.getElementById("console-summary");
+  if (!entry) {
+    summary.textContent = "";
+    params.replaceChildren();
+    body.replaceChildren();
+    return;
+  }
+  const { path, method, op } = entry;
+  summary.replaceChildren(el("span", { class: "method" }, method), el("span", { class: "mono" }, path), " – ", op.summary || "");
+  params.replaceChildren(...(op.parameters || []).map((p) =>
+    el("label", { class: "field" },
+      el("span", {}, el("span", { class: "mono" }, p.name), p.required ? " *" : "",
+        el("div", { class: "hint" }, `${p.in}${p.schema && p.schema.type ? ", " + p.schema.type : ""}${p.description ? " – " + p.description : ""}`)),
+      paramInput(p))));
+
+  const content = (op.requestBody && op.requestBody.content) || {};
+  const mediaTypes = Object.keys(content);
+  if (mediaTypes.length === 0) {
+    body.replaceChildren();
+    return;
+  }
+  const textarea = el("textarea", { id: "console-body-text", spellcheck: "false" });
+  const typeSelect = el("select", { id: "console-content-type" }, mediaTypes.map((t) => el("option", { value: t }, t)));
+  const fill = () => {
+    const media = content[typeSelect.value] || {};
+    const sample = media.example !== undefined ? media.example : sampleFromSchema(media.schema);
+    textarea.value = typeof sample === "string" ? sample : JSON.stringify(sample, null, 2);
+  };
+  typeSelect.addEventListener("change", fill);
+  fill();
+  body.replaceChildren(
+    el("label", { class: "field" }, el("span", {}, "Content type", op.requestBody.required ? " *" : ""), typeSelect),
+    el("label", { class: "field" }, el("span", {}, "Request body", op.requestBody.description ? el("div", { class: "hint" }, op.requestBody.description) : null), textarea),
+  );
+}
+
+function renderConsoleResult(result) {
+  let text = result.body;
+  try {
+    text = JSON.stringify(JSON.parse(result.body), null, 2);
+  } catch (err) {
+    // not JSON, show as-is
+  }
+  const headers = Object.entries(result.headers || {}).sort(([a], [b]) => a.localeCompare(b));
+  document.getElementById("console-result").replaceChildren(
+    el("h2", {}, "Response"),
+    el("div", { class: "summary" },
+      el("span", {}, "Status: ", el("strong", { class: statusClass(result.status) }, result.status)),
+      el("span", {}, "Time: ", el("strong", {}, result.durationMs.toFixed(1) + " ms")),
+      el("span", {}, "Request: ", el("strong", { class: "mono" }, `${result.method} ${result.url}`)),
+      el("span", {}, "Request ID: ", el("span", { class: "mono" }, result.requestId))),
+    el("h3", {}, "Headers"),
+    el("table", {}, el("tbody", {}, headers.map(([name, values]) =>
+      el("tr", {}, el("td", { class: "mono" }, name), el("td", { class: "mono" }, values.join(", ")))))),
+    el("h3", {}, "Body"),
+    el("pre", {}, text),
+  );
+}
+
+async function sendConsoleRequest(ev) {
+  ev.preventDefault();
+  const operationId = document.getElementById("console-operation").value;
+  if (!operationId) return;
+  const params = {};
+  for (const input of document.querySelectorAll("#console-params [data-param]")) {
+    if (input.value !== "") params[input.dataset.param] = input.value;
+  }
+  const request = { operationId, params };
+  const bodyText = document.getElementById("console-body-text");
+  if (bodyText && bodyText.value.trim() !== "") {
+    request.body = bodyText.value;
+    request.contentType = document.getElementById("console-content-type").value;
+  }
+  const target = document.getElementById("console-result");
+  target.replaceChildren(el("p", { class: "muted" }, "Sending…"));
+  try {
+    renderConsoleResult(await api("/admin/invoke", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(request),
+    }));
+  } catch (err) {
+    target.replaceChildren(el("p", { class: "error" }, err.message));
+  }
+}
+
+function connectEvents() {
+  if (events) events.close();
+  const live = document.getElementById("live");
+  events = new EventSource("/admin/events?token=" + encodeURIComponent(token));
+  events.onopen = () => {
+    live.textContent = "live";
+    live.className = "badge live";
+  };
+  events.onerror = () => {
+    live.textContent = "reconnecting";
+    live.className = "badge unknown";
+  };
+  events.addEventListener("request", (ev) => {
+    const req = JSON.parse(ev.data);
+    addRequest(req);
+    if (req.service) scheduleRefresh();
+  });
+  events.addEventListener("config", scheduleRefresh);
+}
+
+function showTab() {
+  const name = (location.hash || "#overview").slice(1);
+  for (const tab of document.querySelectorAll(".tab")) tab.hidden = tab.id !== name;
+  for (const link of document.querySelectorAll("nav a")) link.classList.toggle("active", link.dataset.tab === name);
+}
+
+async function start() {
+  document.getElementById("login").hidden = true;
+  document.getElementById("app").hidden = false;
+  await refresh();
+  const recent = await api("/admin/requests");
+  document.getElementById("request-rows").replaceChildren(...recent.requests.map(requestRow));
+  connectEvents();
+}
+
+function logout(message) {
+  sessionStorage.removeItem("adminToken");
+  token = "";
+  if (events) events.close();
+  document.getElementById("app").hidden = true;
+  document.getElementById("login").hidden = false;
+  document.getElementById("login-error").textContent = message || "";
+}
+
+document.getElementById("login").addEventListener("submit", (ev) => {
+  ev.preventDefault();
+  token = document.getElementById("token").value.trim();
+  sessionStorage.setItem("adminToken", token);
+  start().catch(console.error);
+});
+
+document.getElementById("console-operation").addEventListener("change", (ev) => renderConsoleForm(ev.target.value));
+document.getElementById("console-form").addEventListener("submit", sendConsoleRequest);
+
+window.addEventListener("hashchange", showTab);
+showTab();
+
+const params = new URLSearchParams(location.search);
+if (params.has("token")) {
+  sessionStorage.setItem("adminToken", params.get("token"));
+  history.replaceState(null, "", location.pathname + location.hash);
+}
+token = sessionStorage.getItem("adminToken") || "";
+if (token) {
+  start().catch(console.error);
+} else {
+  logout();
+}
@@ -0,0 +1,34 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRecentRequestsOnlyRecordOperations(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))
+	defer upstream.Close()
+	g := newTestGateway(t, map[string]string{"items.yaml": testService("items", upstream.URL)})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/openapi.json", g.OpenAPIHandler)
+	mux.Handle("/metrics", g.MetricsHandler())
+	mux.HandleFunc("/", g.ProxyHandler)
+	handler := g.RequestIDMiddleware(g.MetricsMiddleware(g.RecentRequestsMiddleware(mux)))
+
+	for _, path := range []string{"/openapi.json", "/metrics", "/unknown", "/items"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	recent := g.RecentRequests()
+	if len(recent) != 1 {
+		t.Fatalf("recorded %d requests, want 1: %+v", len(recent), recent)
+	}
+	if got := recent[0]; got.OperationID != "listItems" || got.Service != "items" || got.Status != http.StatusOK || got.RequestID == "" {
+		t.Fatalf("unexpected entry %+v", got)
+	}
+}
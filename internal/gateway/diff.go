@@ -0,0 +1,585 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// specSettleDelay is how long the document must stay unchanged before it is
+// recorded as a new generation.
+var specSettleDelay = time.Second
+
+var (
+	schemaUpperBounds = []string{"maxLength", "maximum", "exclusiveMaximum", "maxItems", "maxProperties"}
+	schemaLowerBounds = []string{"minLength", "minimum", "exclusiveMinimum", "minItems", "minProperties"}
+)
+
+// SpecChange is one difference between two OpenAPI documents. Breaking
+// changes can make calls from a GPT that imported the old document fail.
+type SpecChange struct {
+	Breaking    bool   `json:"breaking"`
+	OperationID string `json:"operationId,omitempty"`
+	Method      string `json:"method,omitempty"`
+	Path        string `json:"path,omitempty"`
+	Message     string `json:"message"`
+}
+
+type SpecGeneration struct {
+	Generation int       `json:"generation"`
+	Time       time.Time `json:"time"`
+}
+
+type specGeneration struct {
+	SpecGeneration
+	spec []byte
+}
+
+type specOperation struct {
+	method      string
+	path        string
+	shape       string
+	operationID string
+	operation   map[string]any
+}
+
+// DiffOpenAPISpecs compares the request side of two documents built by
+// BuildOpenAPISpec: operations, parameters and request bodies. Response
+// changes and wording changes are not reported.
+func DiffOpenAPISpecs(oldSpec, newSpec []byte) ([]SpecChange, error) {
+	var oldDoc, newDoc map[string]any
+	if err := json.Unmarshal(oldSpec, &oldDoc); err != nil {
+		return nil, fmt.Errorf("invalid old OpenAPI document: %w", err)
+	}
+	if err := json.Unmarshal(newSpec, &newDoc); err != nil {
+		return nil, fmt.Errorf("invalid new OpenAPI document: %w", err)
+	}
+
+	var changes []SpecChange
+	if oldURL, newURL := specServerURL(oldDoc), specServerURL(newDoc); oldURL != newURL {
+		changes = append(changes, SpecChange{Breaking: true, Message: fmt.Sprintf("server URL changed from %q to %q", oldURL, newURL)})
+	}
+
+	oldOps, newOps := collectSpecOperations(oldDoc), collectSpecOperations(newDoc)
+	matched := make(map[int]int)
+	used := make(map[int]bool)
+	for i, o := range oldOps {
+		for j, n := range newOps {
+			if !used[j] && o.operationID != "" && o.operationID == n.operationID {
+				matched[i], used[j] = j, true
+				break
+			}
+		}
+	}
+	for i, o := range oldOps {
+		if _, ok := matched[i]; ok {
+			continue
+		}
+		for j, n := range newOps {
+			if !used[j] && o.method == n.method && o.shape == n.shape {
+				matched[i], used[j] = j, true
+				break
+			}
+		}
+	}
+
+	for i, o := range oldOps {
+		j, ok := matched[i]
+		if !ok {
+			changes = append(changes, o.change(true, "operation was removed"))
+			continue
+		}
+		changes = append(changes, diffOperations(o, newOps[j])...)
+	}
+	for j, n := range newOps {
+		if !used[j] {
+			changes = append(changes, n.change(false, "operation was added"))
+		}
+	}
+	return changes, nil
+}
+
+func specServerURL(doc map[string]any) string {
+	servers, _ := doc["servers"].([]any)
+	if len(servers) == 0 {
+		return ""
+	}
+	server, _ := servers[0].(map[string]any)
+	url, _ := server["url"].(string)
+	return url
+}
+
+func collectSpecOperations(doc map[string]any) []specOperation {
+	var ops []specOperation
+	paths, _ := doc["paths"].(map[string]any)
+	for path, item := range paths {
+		methods, _ := item.(map[string]any)
+		for method, raw := range methods {
+			op, ok := raw.(map[string]any)
+			if !ok {
+				continue
+			}
+			id, _ := op["operationId"].(string)
+			ops = append(ops, specOperation{
+				method:      strings.ToUpper(method),
+				path:        path,
+				shape:       pathShape(path),
+				operationID: id,
+				operation:   op,
+			})
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].path == ops[j].path {
+			return ops[i].method < ops[j].method
+		}
+		return ops[i].path < ops[j].path
+	})
+	return ops
+}
+
+func pathShape(path string) string {
+	segments := strings.Split(path, "/")
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			segments[i] = "{}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func (o specOperation) change(breaking bool, format string, args ...any) SpecChange {
+	return SpecChange{
+		Breaking:    breaking,
+		OperationID: o.operationID,
+		Method:      o.method,
+		Path:        o.path,
+		Message:     fmt.Sprintf(format, args...),
+	}
+}
+
+func diffOperations(o, n specOperation) []SpecChange {
+	var changes []SpecChange
+	report := func(breaking bool, format string, args ...any) {
+		changes = append(changes, n.change(breaking, format, args...))
+	}
+	if o.operationID != n.operationID {
+		report(true, "operationId changed from %q to %q", o.operationID, n.operationID)
+	}
+	if o.method != n.method || o.shape != n.shape {
+		report(true, "operation moved from %s %s to %s %s", o.method, o.path, n.method, n.path)
+	}
+
+	oldParams, newParams := specParameters(o.operation), specParameters(n.operation)
+	keys := make([]string, 0, len(oldParams)+len(newParams))
+	for key := range oldParams {
+		keys = append(keys, key)
+	}
+	for key := range newParams {
+		if _, ok := oldParams[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		op, np := oldParams[key], newParams[key]
+		where := fmt.Sprintf("%s parameter %q", np["in"], np["name"])
+		switch {
+		case np == nil:
+			report(true, "%s parameter %q was removed", op["in"], op["name"])
+		case op == nil && isTrue(np["required"]):
+			report(true, "new required %s", where)
+		case op == nil:
+			report(false, "new optional %s", where)
+		default:
+			if !isTrue(op["required"]) && isTrue(np["required"]) {
+				report(true, "%s is now required", where)
+			} else if isTrue(op["required"]) && !isTrue(np["required"]) {
+				report(false, "%s is now optional", where)
+			}
+			oldSchema, _ := op["schema"].(map[string]any)
+			newSchema, _ := np["schema"].(map[string]any)
+			diffSchemas(oldSchema, newSchema, where, "", report)
+		}
+	}
+
+	oldBody, _ := o.operation["requestBody"].(map[string]any)
+	newBody, _ := n.operation["requestBody"].(map[string]any)
+	switch {
+	case oldBody == nil && newBody == nil:
+	case newBody == nil:
+		report(true, "request body was removed")
+	case oldBody == nil && isTrue(newBody["required"]):
+		report(true, "operation now requires a request body")
+	case oldBody == nil:
+		report(false, "operation accepts an optional request body")
+	default:
+		if !isTrue(oldBody["required"]) && isTrue(newBody["required"]) {
+			report(true, "request body is now required")
+		}
+		oldContent, _ := oldBody["content"].(map[string]any)
+		newContent, _ := newBody["content"].(map[string]any)
+		mediaTypes := make([]string, 0, len(oldContent))
+		for mediaType := range oldContent {
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+		sort.Strings(mediaTypes)
+		for _, mediaType := range mediaTypes {
+			newMedia, ok := newContent[mediaType].(map[string]any)
+			if !ok {
+				report(true, "request body no longer accepts %s", mediaType)
+				continue
+			}
+			oldMedia, _ := oldContent[mediaType].(map[string]any)
+			oldSchema, _ := oldMedia["schema"].(map[string]any)
+			newSchema, _ := newMedia["schema"].(map[string]any)
+			where := "request body"
+			if len(oldContent) > 1 || mediaType != "application/json" {
+				where += " (" + mediaType + ")"
+			}
+			diffSchemas(oldSchema, newSchema, where, "", report)
+		}
+	}
+	return changes
+}
+
+func specParameters(op map[string]any) map[string]map[string]any {
+	params := make(map[string]map[string]any)
+	list, _ := op["parameters"].([]any)
+	for _, raw := range list {
+		param, _ := raw.(map[string]any)
+		name, _ := param["name"].(string)
+		in, _ := param["in"].(string)
+		if name != "" {
+			params[in+":"+name] = param
+		}
+	}
+	return params
+}
+
+// diffSchemas reports where newSchema accepts less than oldSchema. A missing
+// schema accepts anything. field is the dotted path of the property being
+// compared inside the schema that where describes.
+func diffSchemas(oldSchema, newSchema map[string]any, where, field string, report func(bool, string, ...any)) {
+	label := where
+	if field != "" {
+		label = fmt.Sprintf("%s field %q", where, field)
+	}
+	if oldSchema == nil {
+		oldSchema = map[string]any{}
+	}
+	if newSchema == nil {
+		newSchema = map[string]any{}
+	}
+
+	oldTypes, newTypes := schemaTypes(oldSchema), schemaTypes(newSchema)
+	if newTypes != nil {
+		for _, t := range oldTypesOrAny(oldTypes) {
+			if !typeAccepted(t, newTypes) {
+				report(true, "%s type changed from %s to %s", label, describeTypes(oldTypes), describeTypes(newTypes))
+				break
+			}
+		}
+	}
+
+	oldEnum, hasOldEnum := oldSchema["enum"].([]any)
+	newEnum, hasNewEnum := newSchema["enum"].([]any)
+	switch {
+	case hasNewEnum && !hasOldEnum:
+		report(true, "%s is now restricted to %s", label, describeValues(newEnum))
+	case hasNewEnum:
+		if removed := missingValues(oldEnum, newEnum); len(removed) > 0 {
+			report(true, "%s no longer accepts %s", label, describeValues(removed))
+		}
+		if added := missingValues(newEnum, oldEnum); len(added) > 0 {
+			report(false, "%s also accepts %s", label, describeValues(added))
+		}
+	case hasOldEnum:
+		report(false, "%s is no longer restricted to a list of values", label)
+	}
+
+	for _, key := range schemaUpperBounds {
+		newValue, ok := schemaNumber(newSchema[key])
+		if !ok {
+			continue
+		}
+		if oldValue, ok := schemaNumber(oldSchema[key]); !ok {
+			report(true, "%s now has %s %v", label, key, newValue)
+		} else if newValue < oldValue {
+			report(true, "%s %s lowered from %v to %v", label, key, oldValue, newValue)
+		}
+	}
+	for _, key := range schemaLowerBounds {
+		newValue, ok := schemaNumber(newSchema[key])
+		if !ok {
+			continue
+		}
+		if oldValue, ok := schemaNumber(oldSchema[key]); !ok {
+			if newValue > 0 {
+				report(true, "%s now has %s %v", label, key, newValue)
+			}
+		} else if newValue > oldValue {
+			report(true, "%s %s raised from %v to %v", label, key, oldValue, newValue)
+		}
+	}
+	for _, key := range []string{"pattern", "format", "const"} {
+		newValue, ok := newSchema[key]
+		if !ok {
+			continue
+		}
+		if oldValue, ok := oldSchema[key]; !ok || !jsonEqual(oldValue, newValue) {
+			report(true, "%s %s changed to %v", label, key, newValue)
+		}
+	}
+
+	oldRequired := stringSet(oldSchema["required"])
+	newRequired := stringSet(newSchema["required"])
+	for _, name := range sortedKeys(newRequired) {
+		if _, ok := oldRequired[name]; !ok {
+			report(true, "%s field %q is now required", where, joinField(field, name))
+		}
+	}
+
+	if extra, ok := newSchema["additionalProperties"].(bool); ok && !extra {
+		if old, ok := oldSchema["additionalProperties"].(bool); !ok || old {
+			report(true, "%s no longer accepts additional properties", label)
+		}
+	}
+
+	oldProps, _ := oldSchema["properties"].(map[string]any)
+	newProps, _ := newSchema["properties"].(map[string]any)
+	closed := newSchema["additionalProperties"] == false
+	for _, name := range sortedKeys(oldProps) {
+		newProp, ok := newProps[name].(map[string]any)
+		if !ok {
+			if closed {
+				report(true, "%s field %q was removed", where, joinField(field, name))
+			}
+			continue
+		}
+		oldProp, _ := oldProps[name].(map[string]any)
+		diffSchemas(oldProp, newProp, where, joinField(field, name), report)
+	}
+
+	if newItems, ok := newSchema["items"].(map[string]any); ok {
+		oldItems, _ := oldSchema["items"].(map[string]any)
+		diffSchemas(oldItems, newItems, where, field+"[]", report)
+	}
+}
+
+func joinField(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+func schemaTypes(schema map[string]any) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []any:
+		var types []string
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func oldTypesOrAny(types []string) []string {
+	if types == nil {
+		return []string{"any"}
+	}
+	return types
+}
+
+func typeAccepted(t string, accepted []string) bool {
+	for _, a := range accepted {
+		if a == t || (t == "integer" && a == "number") {
+			return true
+		}
+	}
+	return false
+}
+
+func describeTypes(types []string) string {
+	if types == nil {
+		return "any"
+	}
+	return strings.Join(types, "|")
+}
+
+func describeValues(values []any) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		data, _ := json.Marshal(v)
+		parts[i] = string(data)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func missingValues(from, in []any) []any {
+	var missing []any
+	for _, v := range from {
+		found := false
+		for _, w := range in {
+			if jsonEqual(v, w) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, v)
+		}
+	}
+	return missing
+}
+
+func jsonEqual(a, b any) bool {
+	da, errA := json.Marshal(a)
+	db, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(da, db)
+}
+
+func schemaNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func stringSet(v any) map[string]any {
+	set := make(map[string]any)
+	list, _ := v.([]any)
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			set[s] = true
+		}
+	}
+	return set
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func isTrue(v any) bool {
+	b, _ := v.(bool)
+	return b
+}
+
+// recordSpecGenerationLocked keeps the current and previous versions of the
+// generated document so edits can be checked for breaking changes. Nothing
+// is recorded until the initial load has finished, and a generation is only
+// recorded once changes have settled: saving a file often passes through
+// intermediate states, such as the file briefly missing, that must not
+// become the "previous" document or bump the version.
+func (g *Gateway) recordSpecGenerationLocked() {
+	g.invalidateSpecCacheLocked()
+	if g.specCurrent == nil {
+		return
+	}
+	g.specSettleSeq++
+	seq := g.specSettleSeq
+	if g.specSettle != nil {
+		g.specSettle.Stop()
+	}
+	g.specSettle = time.AfterFunc(specSettleDelay, func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if g.specSettleSeq == seq {
+			g.pushSpecGenerationLocked()
+		}
+	})
+}
+
+func (g *Gateway) pushSpecGenerationLocked() {
+	g.updateAPIVersionLocked()
+	g.invalidateSpecCacheLocked()
+	spec, err := json.Marshal(g.openAPIDocumentLocked(""))
+	if err != nil {
+		g.logger.Error("failed to snapshot OpenAPI spec", "error", err)
+		return
+	}
+	next := 1
+	if g.specCurrent != nil {
+		if bytes.Equal(g.specCurrent.spec, spec) {
+			return
+		}
+		next = g.specCurrent.Generation + 1
+	}
+	g.specPrevious = g.specCurrent
+	g.specCurrent = &specGeneration{SpecGeneration: SpecGeneration{Generation: next, Time: time.Now().UTC()}, spec: spec}
+	if g.specPrevious == nil {
+		return
+	}
+	changes, err := DiffOpenAPISpecs(g.specPrevious.spec, spec)
+	if err != nil {
+		return
+	}
+	breaking := 0
+	for _, c := range changes {
+		if c.Breaking {
+			breaking++
+		}
+	}
+	if breaking > 0 {
+		g.logger.Warn("OpenAPI spec has breaking changes; GPTs must re-import it", "generation", next, "breaking", breaking, "changes", len(changes))
+	}
+}
+
+// SpecDiff compares the current generation of the document with the one
+// before it. previous is nil when the spec has not changed since startup.
+func (g *Gateway) SpecDiff() (current, previous *SpecGeneration, changes []SpecChange, err error) {
+	g.mu.RLock()
+	cur, prev := g.specCurrent, g.specPrevious
+	g.mu.RUnlock()
+	if cur == nil {
+		return nil, nil, nil, nil
+	}
+	if prev == nil {
+		return &cur.SpecGeneration, nil, nil, nil
+	}
+	changes, err = DiffOpenAPISpecs(prev.spec, cur.spec)
+	return &cur.SpecGeneration, &prev.SpecGeneration, changes, err
+}
+
+func (g *Gateway) adminDiff(w http.ResponseWriter, r *http.Request) {
+	current, previous, changes, err := g.SpecDiff()
+	if err != nil {
+		g.loggerFor(r.Context()).Error("failed to diff OpenAPI spec", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "failed to diff OpenAPI spec")
+		return
+	}
+	breaking := false
+	for _, c := range changes {
+		breaking = breaking || c.Breaking
+	}
+	if changes == nil {
+		changes = []SpecChange{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"current":  current,
+		"previous": previous,
+		"breaking": breaking,
+		"changes":  changes,
+	})
+}
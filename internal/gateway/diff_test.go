@@ -0,0 +1,365 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// specWithOperations builds a minimal document with the given operations,
+// each keyed by "METHOD /path".
+func specWithOperations(t *testing.T, ops map[string]map[string]any) []byte {
+	t.Helper()
+	paths := make(map[string]any)
+	for key, op := range ops {
+		method, path, _ := strings.Cut(key, " ")
+		item, _ := paths[path].(map[string]any)
+		if item == nil {
+			item = make(map[string]any)
+			paths[path] = item
+		}
+		item[strings.ToLower(method)] = op
+	}
+	data, err := json.Marshal(map[string]any{
+		"openapi": "3.1.0",
+		"servers": []any{map[string]any{"url": "https://gateway.example"}},
+		"paths":   paths,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func queryParam(name string, required bool, schema map[string]any) map[string]any {
+	return map[string]any{"name": name, "in": "query", "required": required, "schema": schema}
+}
+
+func jsonBody(required bool, schema map[string]any) map[string]any {
+	return map[string]any{
+		"required": required,
+		"content":  map[string]any{"application/json": map[string]any{"schema": schema}},
+	}
+}
+
+func TestDiffOpenAPISpecs(t *testing.T) {
+	getItem := map[string]any{"operationId": "getItem"}
+	taskBody := func(schema map[string]any) map[string]any {
+		return map[string]any{"operationId": "addTask", "requestBody": jsonBody(true, schema)}
+	}
+
+	tests := []struct {
+		name     string
+		old, new map[string]map[string]any
+		want     []SpecChange
+	}{
+		{
+			name: "unchanged",
+			old:  map[string]map[string]any{"GET /items/{id}": getItem},
+			new:  map[string]map[string]any{"GET /items/{id}": getItem},
+		},
+		{
+			name: "operation removed",
+			old:  map[string]map[string]any{"GET /items/{id}": getItem, "GET /other": {"operationId": "getOther"}},
+			new:  map[string]map[string]any{"GET /items/{id}": getItem},
+			want: []SpecChange{{Breaking: true, OperationID: "getOther", Method: "GET", Path: "/other", Message: "operation was removed"}},
+		},
+		{
+			name: "operation added",
+			old:  map[string]map[string]any{"GET /items/{id}": getItem},
+			new:  map[string]map[string]any{"GET /items/{id}": getItem, "GET /other": {"operationId": "getOther"}},
+			want: []SpecChange{{OperationID: "getOther", Method: "GET", Path: "/other", Message: "operation was added"}},
+		},
+		{
+			name: "operationId renamed",
+			old:  map[string]map[string]any{"GET /items/{id}": getItem},
+			new:  map[string]map[string]any{"GET /items/{id}": {"operationId": "fetchItem"}},
+			want: []SpecChange{{Breaking: true, OperationID: "fetchItem", Method: "GET", Path: "/items/{id}", Message: `operationId changed from "getItem" to "fetchItem"`}},
+		},
+		{
+			name: "path parameter renamed is not a change",
+			old:  map[string]map[string]any{"GET /items/{id}": getItem},
+			new:  map[string]map[string]any{"GET /items/{itemId}": getItem},
+		},
+		{
+			name: "operation moved",
+			old:  map[string]map[string]any{"GET /items/{id}": getItem},
+			new:  map[string]map[string]any{"GET /things/{id}": getItem},
+			want: []SpecChange{{Breaking: true, OperationID: "getItem", Method: "GET", Path: "/things/{id}", Message: "operation moved from GET /items/{id} to GET /things/{id}"}},
+		},
+		{
+			name: "new required parameter",
+			old:  map[string]map[string]any{"GET /search": {"operationId": "search"}},
+			new: map[string]map[string]any{"GET /search": {"operationId": "search", "parameters": []any{
+				queryParam("q", true, map[string]any{"type": "string"}),
+				queryParam("limit", false, map[string]any{"type": "integer"}),
+			}}},
+			want: []SpecChange{
+				{OperationID: "search", Method: "GET", Path: "/search", Message: `new optional query parameter "limit"`},
+				{Breaking: true, OperationID: "search", Method: "GET", Path: "/search", Message: `new required query parameter "q"`},
+			},
+		},
+		{
+			name: "parameter now required",
+			old: map[string]map[string]any{"GET /search": {"operationId": "search", "parameters": []any{
+				queryParam("q", false, map[string]any{"type": "string"}),
+			}}},
+			new: map[string]map[string]any{"GET /search": {"operationId": "search", "parameters": []any{
+				queryParam("q", true, map[string]any{"type": "string"}),
+			}}},
+			want: []SpecChange{{Breaking: true, OperationID: "search", Method: "GET", Path: "/search", Message: `query parameter "q" is now required`}},
+		},
+		{
+			name: "parameter removed",
+			old: map[string]map[string]any{"GET /search": {"operationId": "search", "parameters": []any{
+				queryParam("q", false, map[string]any{"type": "string"}),
+			}}},
+			new:  map[string]map[string]any{"GET /search": {"operationId": "search"}},
+			want: []SpecChange{{Breaking: true, OperationID: "search", Method: "GET", Path: "/search", Message: `query parameter "q" was removed`}},
+		},
+		{
+			name: "request body field now required",
+			old: map[string]map[string]any{"POST /tasks": taskBody(map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"task": map[string]any{"type": "string"}},
+			})},
+			new: map[string]map[string]any{"POST /tasks": taskBody(map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"task": map[string]any{"type": "string"}},
+				"required":   []any{"task"},
+			})},
+			want: []SpecChange{{Breaking: true, OperationID: "addTask", Method: "POST", Path: "/tasks", Message: `request body field "task" is now required`}},
+		},
+		{
+			name: "request body now required",
+			old:  map[string]map[string]any{"POST /tasks": {"operationId": "addTask", "requestBody": jsonBody(false, nil)}},
+			new:  map[string]map[string]any{"POST /tasks": {"operationId": "addTask", "requestBody": jsonBody(true, nil)}},
+			want: []SpecChange{{Breaking: true, OperationID: "addTask", Method: "POST", Path: "/tasks", Message: "request body is now required"}},
+		},
+		{
+			name: "enum narrowed and widened",
+			old: map[string]map[string]any{"POST /tasks": taskBody(map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"priority": map[string]any{"enum": []any{"low", "high"}}},
+			})},
+			new: map[string]map[string]any{"POST /tasks": taskBody(map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"priority": map[string]any{"enum": []any{"low", "urgent"}}},
+			})},
+			want: []SpecChange{
+				{Breaking: true, OperationID: "addTask", Method: "POST", Path: "/tasks", Message: `request body field "priority" no longer accepts "high"`},
+				{OperationID: "addTask", Method: "POST", Path: "/tasks", Message: `request body field "priority" also accepts "urgent"`},
+			},
+		},
+		{
+			name: "type narrowed",
+			old: map[string]map[string]any{"GET /search": {"operationId": "search", "parameters": []any{
+				queryParam("limit", false, map[string]any{"type": "number"}),
+			}}},
+			new: map[string]map[string]any{"GET /search": {"operationId": "search", "parameters": []any{
+				queryParam("limit", false, map[string]any{"type": "integer"}),
+			}}},
+			want: []SpecChange{{Breaking: true, OperationID: "search", Method: "GET", Path: "/search", Message: `query parameter "limit" type changed from number to integer`}},
+		},
+		{
+			name: "type widened",
+			old: map[string]map[string]any{"GET /search": {"operationId": "search", "parameters": []any{
+				queryParam("limit", false, map[string]any{"type": "integer"}),
+			}}},
+			new: map[string]map[string]any{"GET /search": {"operationId": "search", "parameters": []any{
+				queryParam("limit", false, map[string]any{"type": "number"}),
+			}}},
+		},
+		{
+			name: "bounds narrowed",
+			old: map[string]map[string]any{"GET /search": {"operationId": "search", "parameters": []any{
+				queryParam("q", false, map[string]any{"type": "string", "maxLength": 100}),
+			}}},
+			new: map[string]map[string]any{"GET /search": {"operationId": "search", "parameters": []any{
+				queryParam("q", false, map[string]any{"type": "string", "maxLength": 50, "minLength": 2}),
+			}}},
+			want: []SpecChange{
+				{Breaking: true, OperationID: "search", Method: "GET", Path: "/search", Message: `query parameter "q" maxLength lowered from 100 to 50`},
+				{Breaking: true, OperationID: "search", Method: "GET", Path: "/search", Message: `query parameter "q" now has minLength 2`},
+			},
+		},
+		{
+			name: "nested array item narrowed",
+			old: map[string]map[string]any{"POST /tasks": taskBody(map[string]any{
+				"type": "object",
+				"properties": map[string]any{"tags": map[string]any{
+					"type": "array", "items": map[string]any{"type": "string"},
+				}},
+			})},
+			new: map[string]map[string]any{"POST /tasks": taskBody(map[string]any{
+				"type": "object",
+				"properties": map[string]any{"tags": map[string]any{
+					"type": "array", "items": map[string]any{"type": "string", "pattern": "^[a-z]+$"},
+				}},
+			})},
+			want: []SpecChange{{Breaking: true, OperationID: "addTask", Method: "POST", Path: "/tasks", Message: `request body field "tags[]" pattern changed to ^[a-z]+$`}},
+		},
+		{
+			name: "closed object drops a field",
+			old: map[string]map[string]any{"POST /tasks": taskBody(map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"task": map[string]any{"type": "string"}, "note": map[string]any{"type": "string"}},
+			})},
+			new: map[string]map[string]any{"POST /tasks": taskBody(map[string]any{
+				"type":                 "object",
+				"properties":           map[string]any{"task": map[string]any{"type": "string"}},
+				"additionalProperties": false,
+			})},
+			want: []SpecChange{
+				{Breaking: true, OperationID: "addTask", Method: "POST", Path: "/tasks", Message: "request body no longer accepts additional properties"},
+				{Breaking: true, OperationID: "addTask", Method: "POST", Path: "/tasks", Message: `request body field "note" was removed`},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DiffOpenAPISpecs(specWithOperations(t, tt.old), specWithOperations(t, tt.new))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d changes, want %d:\n%+v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("change %d:\n got %+v\nwant %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDiffOpenAPISpecsServerURL(t *testing.T) {
+	oldSpec := []byte(`{"servers": [{"url": "https://a.example"}], "paths": {}}`)
+	newSpec := []byte(`{"servers": [{"url": "https://b.example"}], "paths": {}}`)
+	got, err := DiffOpenAPISpecs(oldSpec, newSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || !got[0].Breaking || !strings.Contains(got[0].Message, "server URL changed") {
+		t.Fatalf("unexpected changes %+v", got)
+	}
+}
+
+func TestDiffOpenAPISpecsInvalidJSON(t *testing.T) {
+	if _, err := DiffOpenAPISpecs([]byte(`{`), []byte(`{}`)); err == nil {
+		t.Fatal("expected an error for an invalid old document")
+	}
+}
+
+func TestSpecDiffBetweenLoadedGenerations(t *testing.T) {
+	defer func(delay time.Duration) { specSettleDelay = delay }(specSettleDelay)
+	specSettleDelay = 20 * time.Millisecond
+
+	v1 := `serviceName: items
+serviceAddress: http://127.0.0.1:1
+description: Items.
+endpoints:
+  - path: /items
+    method: GET
+    operationId: listItems
+    description: List items.
+    parameters:
+      - {name: limit, in: query, schema: {type: number}}
+  - path: /items/{id}
+    method: GET
+    operationId: getItem
+    description: Get an item.
+    parameters:
+      - {name: id, in: path, required: true, schema: {type: string}}
+  - path: /items
+    method: POST
+    operationId: addItem
+    description: Add an item.
+    requestBody:
+      required: true
+      content:
+        application/json:
+          schema:
+            type: object
+            properties:
+              name: {type: string, maxLength: 100}
+              tag: {type: string, enum: [a, b]}
+`
+	v2 := `serviceName: items
+serviceAddress: http://127.0.0.1:1
+description: Items.
+endpoints:
+  - path: /items
+    method: GET
+    operationId: listItems
+    description: List items.
+    parameters:
+      - {name: limit, in: query, schema: {type: integer}}
+      - {name: owner, in: query, required: true, schema: {type: string}}
+  - path: /items
+    method: POST
+    operationId: createItem
+    description: Add an item.
+    requestBody:
+      required: true
+      content:
+        application/json:
+          schema:
+            type: object
+            properties:
+              name: {type: string, maxLength: 50}
+              tag: {type: string, enum: [a]}
+            required: [name]
+  - path: /items/search
+    method: GET
+    operationId: searchItems
+    description: Search items.
+`
+	g := newTestGateway(t, map[string]string{"items.yaml": v1})
+	if current, previous, _, _ := g.SpecDiff(); current == nil || current.Generation != 1 || previous != nil {
+		t.Fatalf("initial generations %+v, %+v", current, previous)
+	}
+
+	writeTestFile(t, filepath.Join(g.ConfigDir(), "items.yaml"), v2)
+	g.loadService(filepath.Join(g.ConfigDir(), "items.yaml"))
+	var (
+		current, previous *SpecGeneration
+		changes           []SpecChange
+		err               error
+	)
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(specSettleDelay) {
+		if current, previous, changes, err = g.SpecDiff(); err != nil {
+			t.Fatal(err)
+		}
+		if current.Generation == 2 || time.Now().After(deadline) {
+			break
+		}
+	}
+	if current.Generation != 2 || previous == nil || previous.Generation != 1 {
+		t.Fatalf("generations %+v, %+v", current, previous)
+	}
+
+	got := make([]string, 0, len(changes))
+	for _, c := range changes {
+		got = append(got, fmt.Sprintf("%v %s %s %s: %s", c.Breaking, c.OperationID, c.Method, c.Path, c.Message))
+	}
+	want := []string{
+		`false searchItems GET /items/search: operation was added`,
+		`true createItem POST /items: operationId changed from "addItem" to "createItem"`,
+		`true createItem POST /items: request body field "name" is now required`,
+		`true createItem POST /items: request body field "name" maxLength lowered from 100 to 50`,
+		`true createItem POST /items: request body field "tag" no longer accepts "b"`,
+		`true getItem GET /items/{id}: operation was removed`,
+		`true listItems GET /items: new required query parameter "owner"`,
+		`true listItems GET /items: query parameter "limit" type changed from number to integer`,
+	}
+	sort.Strings(got)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("changes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
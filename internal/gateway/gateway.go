@@ -2,10 +2,11 @@ package gateway
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
@@ -19,16 +20,68 @@ import (
 
 var ErrNoMatchingRoute = errors.New("no matching route found")
 
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return e.Message
+}
+
 type Gateway struct {
-	mu            sync.RWMutex
-	services      map[string]*Service
-	fileToService map[string]string
-	routes        map[string][]*route
-	client        *http.Client
-	configDir     string
+	mu               sync.RWMutex
+	services         map[string]*Service
+	fileToService    map[string]string
+	routes           map[string][]*route
+	client           *http.Client
+	cache            *responseCache
+	maxRequestBytes  int64
+	metrics          *metrics
+	logger           *slog.Logger
+	audit            *AuditLogger
+	health           *healthTracker
+	state            *stateStore
+	recent           *requestLog
+	events           *eventBroker
+	listen           ListenConfig
+	specCache        specCache
+	specCacheGen     uint64
+	openAPIInfo      OpenAPIInfo
+	apiVersion       int
+	cors             CORSConfig
+	apiKeys          []string
+	responseDefaults ResponseLimit
+	specCurrent      *specGeneration
+	specPrevious     *specGeneration
+	specSettle       *time.Timer
+	specSettleSeq    uint64
+	files            map[string]*FileStatus
+	lastReload       time.Time
+	startedAt        time.Time
+	configDir        string
+	tlsDeps          map[string]map[string]bool
+	watcher          *fsnotify.Watcher
+	watchedDirs      map[string]bool
 }
 
-func New(configDir string) (*Gateway, error) {
+type Option func(*Gateway)
+
+func WithCacheLimits(maxEntries int, maxBytes int64) Option {
+	return func(g *Gateway) {
+		g.cache = newResponseCache(maxEntries, maxBytes)
+	}
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(g *Gateway) {
+		if logger != nil {
+			g.logger = logger
+		}
+	}
+}
+
+func New(configDir string, opts ...Option) (*Gateway, error) {
 	absDir, err := filepath.Abs(configDir)
 	if err != nil {
 		return nil, err
@@ -43,7 +96,26 @@ func New(configDir string) (*Gateway, error) {
 		client: &http.Client{
 			Timeout: 60 * time.Second,
 		},
-		configDir: absDir,
+		configDir:       absDir,
+		tlsDeps:         make(map[string]map[string]bool),
+		watchedDirs:     map[string]bool{absDir: true},
+		cache:           newResponseCache(defaultCacheMaxEntries, defaultCacheMaxBytes),
+		maxRequestBytes: defaultMaxRequestBytes,
+		metrics:         newMetrics(),
+		logger:          slog.Default(),
+		health:          newHealthTracker(),
+		state:           &stateStore{},
+		recent:          newRequestLog(recentRequestsSize),
+		events:          newEventBroker(),
+		files:           make(map[string]*FileStatus),
+		startedAt:       time.Now(),
+	}
+	g.applyServerConfigLocked(DefaultServerConfig())
+	for _, opt := range opts {
+		opt(g)
+	}
+	if err := g.state.load(); err != nil {
+		return nil, err
 	}
 	return g, nil
 }
@@ -67,6 +139,11 @@ func (g *Gateway) LoadExisting() error {
 		}
 		g.loadService(path)
 	}
+	g.mu.Lock()
+	if g.specCurrent == nil {
+		g.pushSpecGenerationLocked()
+	}
+	g.mu.Unlock()
 	return nil
 }
 
@@ -80,15 +157,26 @@ func (g *Gateway) Watch(ctx context.Context) error {
 		return fmt.Errorf("unable to watch directory %s: %w", g.configDir, err)
 	}
 
+	g.mu.Lock()
+	g.watcher = watcher
+	g.watchTLSDirsLocked()
+	g.mu.Unlock()
+
 	go func() {
-		defer watcher.Close()
+		defer func() {
+			g.mu.Lock()
+			g.watcher = nil
+			g.watchedDirs = map[string]bool{g.configDir: true}
+			g.mu.Unlock()
+			watcher.Close()
+		}()
 		for {
 			select {
 			case event := <-watcher.Events:
 				g.handleWatcherEvent(event)
 			case err := <-watcher.Errors:
 				if err != nil {
-					log.Printf("[gateway] watcher error: %v", err)
+					g.logger.Error("config watcher error", "error", err)
 				}
 			case <-ctx.Done():
 				return
@@ -103,19 +191,35 @@ func (g *Gateway) handleWatcherEvent(event fsnotify.Event) {
 	if event.Name == "" {
 		return
 	}
+	if sources := g.tlsDependents(event.Name); len(sources) > 0 {
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			for _, source := range sources {
+				g.loadService(source)
+			}
+		}()
+		return
+	}
 	if !isYAMLFile(event.Name) {
 		if event.Op&fsnotify.Rename != 0 {
 			go g.refreshDirectory()
 		}
 		return
 	}
-	if event.Op&fsnotify.Rename != 0 {
-		g.removeService(event.Name)
-		go g.refreshDirectory()
-		return
-	}
-	if event.Op&fsnotify.Remove != 0 {
-		g.removeService(event.Name)
+	if event.Op&(fsnotify.Rename|fsnotify.Remove) != 0 {
+		// Editors often save by renaming the file away and writing a new
+		// copy, so the service is only removed if the file stays missing.
+		go func(path string, renamed bool) {
+			time.Sleep(200 * time.Millisecond)
+			if _, err := os.Stat(path); err == nil {
+				g.loadService(path)
+			} else {
+				g.removeService(path)
+			}
+			if renamed {
+				g.refreshDirectory()
+			}
+		}(event.Name, event.Op&fsnotify.Rename != 0)
 		return
 	}
 	if event.Op&(fsnotify.Write|fsnotify.Create) != 0 {
@@ -129,9 +233,24 @@ func (g *Gateway) handleWatcherEvent(event fsnotify.Event) {
 func (g *Gateway) loadService(path string) {
 	svc, err := LoadService(path)
 	if err != nil {
-		log.Printf("[gateway] failed to load service from %s: %v", filepath.Base(path), err)
+		g.logger.Error("failed to load service", "file", filepath.Base(path), "error", err)
+		g.metrics.reloads.WithLabelValues("failure").Inc()
+		g.recordFileStatus(path, "", err)
 		return
 	}
+	// Watch the referenced TLS files before building the client, so a
+	// certificate that does not exist yet triggers a reload once it appears.
+	g.mu.Lock()
+	g.setTLSDepsLocked(path, svc.TLS)
+	g.mu.Unlock()
+	client, err := newServiceClient(svc, g.client.Timeout)
+	if err != nil {
+		g.logger.Error("failed to configure TLS for service", "service", svc.Name, "file", filepath.Base(path), "error", err)
+		g.metrics.reloads.WithLabelValues("failure").Inc()
+		g.recordFileStatus(path, svc.Name, err)
+		return
+	}
+	svc.client = client
 
 	g.mu.Lock()
 	defer g.mu.Unlock()
@@ -139,48 +258,74 @@ func (g *Gateway) loadService(path string) {
 	if oldName, ok := g.fileToService[path]; ok && oldName != svc.Name {
 		delete(g.services, oldName)
 	}
+	if old, ok := g.services[svc.Name]; ok {
+		old.closeIdleConnections()
+	}
 	g.services[svc.Name] = svc
 	g.fileToService[path] = svc.Name
+	g.recordFileStatusLocked(path, svc.Name, nil)
 	g.rebuildRoutesLocked()
+	g.metrics.reloads.WithLabelValues("success").Inc()
 
-	log.Printf("[gateway] loaded service %q from %s", svc.Name, filepath.Base(path))
+	g.logger.Info("loaded service", "service", svc.Name, "file", filepath.Base(path))
 }
 
 func (g *Gateway) removeService(path string) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
 
+	delete(g.files, path)
 	name, ok := g.fileToService[path]
 	if !ok {
 		return
 	}
 	delete(g.fileToService, path)
+	g.setTLSDepsLocked(path, nil)
+	for _, other := range g.fileToService {
+		if other == name {
+			// The service moved to another file, which has already loaded it.
+			g.logger.Info("service file renamed", "service", name, "file", filepath.Base(path))
+			return
+		}
+	}
+	if svc, ok := g.services[name]; ok {
+		svc.closeIdleConnections()
+	}
 	delete(g.services, name)
+	g.health.forget(name)
 	g.rebuildRoutesLocked()
-	log.Printf("[gateway] removed service %q (source %s)", name, filepath.Base(path))
+	g.logger.Info("removed service", "service", name, "file", filepath.Base(path))
 }
 
 func (g *Gateway) rebuildRoutesLocked() {
 	routes := make(map[string][]*route)
+	count := 0
 	for _, svc := range g.services {
 		for _, ep := range svc.Endpoints {
 			rt, err := newRoute(svc, ep)
 			if err != nil {
-				log.Printf("[gateway] skipping endpoint %s %s: %v", ep.Method, ep.Path, err)
+				g.logger.Warn("skipping endpoint", "service", svc.Name, "method", ep.Method, "path", ep.Path, "error", err)
 				continue
 			}
 			method := strings.ToUpper(ep.Method)
 			routes[method] = append(routes[method], rt)
+			count++
 		}
 	}
 	g.routes = routes
+	g.lastReload = time.Now()
+	g.invalidateSpecCacheLocked()
+	g.metrics.services.Set(float64(len(g.services)))
+	g.metrics.routes.Set(float64(count))
+	g.recordSpecGenerationLocked()
+	g.events.publish("config", nil)
 }
 
 func (g *Gateway) refreshDirectory() {
 	time.Sleep(300 * time.Millisecond)
 	entries, err := os.ReadDir(g.configDir)
 	if err != nil {
-		log.Printf("[gateway] failed to refresh directory: %v", err)
+		g.logger.Error("failed to refresh config directory", "error", err)
 		return
 	}
 	for _, entry := range entries {
@@ -217,10 +362,53 @@ func (g *Gateway) ProxyRequest(w http.ResponseWriter, r *http.Request) error {
 	if rt == nil {
 		return ErrNoMatchingRoute
 	}
+	rt.annotate(r)
+	if !g.serviceEnabled(rt.service) {
+		return &StatusError{Code: http.StatusServiceUnavailable, Message: fmt.Sprintf("service %s is disabled", rt.service.Name)}
+	}
+	if !g.endpointEnabled(rt.service, rt.endpoint) {
+		return &StatusError{Code: http.StatusServiceUnavailable, Message: fmt.Sprintf("operation %s is disabled", operationIDFor(rt.service, rt.endpoint))}
+	}
+	if err := g.checkRequestBody(r, rt); err != nil {
+		return err
+	}
+	if rt.endpoint.Cache != nil && r.Method == http.MethodGet {
+		return g.proxyCached(w, r, rt, targetPath)
+	}
 
+	req, err := g.newUpstreamRequest(r, rt, targetPath)
+	if err != nil {
+		return err
+	}
+	// A response limit has to measure and possibly truncate the decoded body,
+	// so let the transport negotiate and undo compression itself.
+	if rt.responseLimit(g.responseLimitDefault()).MaxBytes > 0 {
+		req.Header.Del("Accept-Encoding")
+	}
+	resp, err := g.doUpstream(rt, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := g.limitResponse(rt, resp)
+	if err != nil {
+		return err
+	}
+	copyResponseHeaders(w.Header(), resp.Header)
+	w.WriteHeader(resp.StatusCode)
+	if r.Method != http.MethodHead && resp.Body != nil {
+		if _, err := io.Copy(w, body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *Gateway) newUpstreamRequest(r *http.Request, rt *route, targetPath string) (*http.Request, error) {
 	baseURL, err := url.Parse(rt.service.Address)
 	if err != nil {
-		return fmt.Errorf("invalid service address for %s: %w", rt.service.Name, err)
+		return nil, fmt.Errorf("invalid service address for %s: %w", rt.service.Name, err)
 	}
 
 	requestURL := &url.URL{Path: targetPath, RawQuery: r.URL.RawQuery}
@@ -228,9 +416,19 @@ func (g *Gateway) ProxyRequest(w http.ResponseWriter, r *http.Request) error {
 
 	req, err := http.NewRequestWithContext(r.Context(), r.Method, fullURL.String(), r.Body)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	req.ContentLength = r.ContentLength
+	if r.ContentLength == 0 {
+		req.Body = http.NoBody
 	}
 	copyHeaders(req.Header, r.Header)
+	g.mu.RLock()
+	if len(g.apiKeys) > 0 {
+		// The bearer token was the gateway's own API key; services never see it.
+		req.Header.Del("Authorization")
+	}
+	g.mu.RUnlock()
 	req.Header.Set("X-Forwarded-Host", r.Host)
 	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
 		req.Header.Set("X-Forwarded-Proto", proto)
@@ -239,65 +437,83 @@ func (g *Gateway) ProxyRequest(w http.ResponseWriter, r *http.Request) error {
 	} else {
 		req.Header.Set("X-Forwarded-Proto", "http")
 	}
+	return req, nil
+}
 
-	log.Printf("[gateway] proxy %s %s -> %s", r.Method, r.URL.Path, fullURL.String())
-	resp, err := g.client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+// forwardsRequestHeader reports whether a client's request header reaches
+// the service as sent.
+func (g *Gateway) forwardsRequestHeader(name string) bool {
+	switch http.CanonicalHeaderKey(name) {
+	case "Authorization":
+		g.mu.RLock()
+		defer g.mu.RUnlock()
+		return len(g.apiKeys) == 0
+	case "X-Forwarded-Host", requestIDHeader:
+		return false
+	}
+	return !isHopHeader(name)
+}
 
-	copyResponseHeaders(w.Header(), resp.Header)
-	w.WriteHeader(resp.StatusCode)
-	if r.Method != http.MethodHead && resp.Body != nil {
-		if _, err := io.Copy(w, resp.Body); err != nil {
-			return err
+func (g *Gateway) doUpstream(rt *route, req *http.Request) (*http.Response, error) {
+	client := g.client
+	if rt.service.client != nil {
+		client = rt.service.client
+	}
+	g.loggerFor(req.Context()).Debug("proxying request", "service", rt.service.Name, "operationId", operationIDFor(rt.service, rt.endpoint), "method", req.Method, "upstream", req.URL.String())
+	inFlight := g.metrics.upstreamInFlight.WithLabelValues(rt.service.Name, operationIDFor(rt.service, rt.endpoint))
+	inFlight.Inc()
+	defer inFlight.Dec()
+	req, span := startUpstreamSpan(rt, req)
+	resp, err := client.Do(req)
+	endUpstreamSpan(span, resp, err)
+	if !errors.Is(err, context.Canceled) {
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
 		}
+		g.health.record(rt.service.Name, err, status)
 	}
-	return nil
+	g.observeUpstream(rt, resp, err)
+	return resp, err
 }
 
 func (g *Gateway) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
-	setCORSHeaders(w)
+	g.serveOpenAPI(w, r, "json")
+}
+
+func (g *Gateway) OpenAPIYAMLHandler(w http.ResponseWriter, r *http.Request) {
+	g.serveOpenAPI(w, r, "yaml")
+}
+
+func (g *Gateway) ProxyHandler(w http.ResponseWriter, r *http.Request) {
+	g.setCORSHeaders(w, r)
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
-	scheme := "http"
-	if forwarded := r.Header.Get("X-Forwarded-Proto"); forwarded != "" {
-		scheme = forwarded
-	} else if r.TLS != nil {
-		scheme = "https"
-	}
-	baseURL := fmt.Sprintf("%s://%s", scheme, r.Host)
-	payload, err := g.BuildOpenAPISpec(baseURL)
-	if err != nil {
-		log.Printf("[gateway] failed to build OpenAPI spec: %v", err)
-		http.Error(w, "failed to build OpenAPI spec", http.StatusInternalServerError)
+	if !g.requireAPIKey(w, r) {
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write(payload)
+	if err := g.ProxyRequest(w, r); err != nil {
+		g.writeProxyError(w, r, err)
+	}
 }
 
-func (g *Gateway) ProxyHandler(w http.ResponseWriter, r *http.Request) {
-	setCORSHeaders(w)
-	if r.Method == http.MethodOptions {
-		w.WriteHeader(http.StatusNoContent)
+func (g *Gateway) writeProxyError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, ErrNoMatchingRoute) {
+		writeError(w, r, http.StatusNotFound, "no matching endpoint")
 		return
 	}
-	if err := g.ProxyRequest(w, r); err != nil {
-		if errors.Is(err, ErrNoMatchingRoute) {
-			http.Error(w, "no matching endpoint", http.StatusNotFound)
-			return
-		}
-		if errors.Is(err, context.Canceled) {
-			return
-		}
-		log.Printf("[gateway] proxy error: %v", err)
-		http.Error(w, "proxy error", http.StatusBadGateway)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		writeError(w, r, statusErr.Code, statusErr.Message)
+		return
 	}
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	g.loggerFor(r.Context()).Error("proxy error", "method", r.Method, "path", r.URL.Path, "error", err)
+	writeError(w, r, http.StatusBadGateway, "proxy error")
 }
 
 func (g *Gateway) ServicesSnapshot() []*Service {
@@ -348,6 +564,9 @@ func copyResponseHeaders(dst, src http.Header) {
 		if isHopHeader(k) {
 			continue
 		}
+		if http.CanonicalHeaderKey(k) == requestIDHeader && dst.Get(k) != "" {
+			continue
+		}
 		for _, v := range vals {
 			dst.Add(k, v)
 		}
@@ -364,8 +583,14 @@ func isHopHeader(name string) bool {
 	return false
 }
 
-func setCORSHeaders(w http.ResponseWriter) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+func writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	body := map[string]string{"error": message}
+	if info := requestInfoFrom(r.Context()); info != nil && info.requestID != "" {
+		body["requestId"] = info.requestID
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
 }
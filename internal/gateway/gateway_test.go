@@ -0,0 +1,63 @@
+package gateway
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestGateway loads the given service files, keyed by file name, into a
+// gateway over a temporary config directory.
+func newTestGateway(t *testing.T, files map[string]string, opts ...Option) *Gateway {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		writeTestFile(t, filepath.Join(dir, name), content)
+	}
+	opts = append([]Option{WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))}, opts...)
+	g, err := New(dir, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.LoadExisting(); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testService returns a service definition for the given upstream address
+// with a GET /items and a POST /items operation.
+func testService(name, address string) string {
+	return `serviceName: ` + name + `
+serviceAddress: ` + address + `
+description: Test service.
+endpoints:
+  - path: /items
+    method: GET
+    operationId: listItems
+    description: List items.
+  - path: /items
+    method: POST
+    operationId: addItem
+    description: Add an item.
+    requestBody:
+      required: true
+      content:
+        application/json:
+          schema:
+            type: object
+            properties:
+              name:
+                type: string
+            required: [name]
+`
+}
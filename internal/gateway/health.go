@@ -0,0 +1,75 @@
+package gateway
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const unhealthyAfterFailures = 3
+
+type ServiceHealth struct {
+	State               string     `json:"state"`
+	LastSuccess         *time.Time `json:"lastSuccess,omitempty"`
+	LastFailure         *time.Time `json:"lastFailure,omitempty"`
+	LastError           string     `json:"lastError,omitempty"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+}
+
+type healthTracker struct {
+	mu       sync.Mutex
+	services map[string]*ServiceHealth
+}
+
+func newHealthTracker() *healthTracker {
+	return &healthTracker{services: make(map[string]*ServiceHealth)}
+}
+
+func (h *healthTracker) record(service string, err error, status int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	state, ok := h.services[service]
+	if !ok {
+		state = &ServiceHealth{}
+		h.services[service] = state
+	}
+	now := time.Now()
+	switch {
+	case err != nil:
+		state.LastFailure = &now
+		state.LastError = err.Error()
+		state.ConsecutiveFailures++
+	case status >= 500:
+		state.LastFailure = &now
+		state.LastError = fmt.Sprintf("upstream returned %d", status)
+		state.ConsecutiveFailures++
+	default:
+		state.LastSuccess = &now
+		state.ConsecutiveFailures = 0
+	}
+}
+
+func (h *healthTracker) get(service string) ServiceHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	state, ok := h.services[service]
+	if !ok {
+		return ServiceHealth{State: "unknown"}
+	}
+	out := *state
+	switch {
+	case out.ConsecutiveFailures >= unhealthyAfterFailures:
+		out.State = "unhealthy"
+	case out.ConsecutiveFailures > 0:
+		out.State = "degraded"
+	default:
+		out.State = "healthy"
+	}
+	return out
+}
+
+func (h *healthTracker) forget(service string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.services, service)
+}
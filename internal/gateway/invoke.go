@@ -0,0 +1,208 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const maxInvokeRequestBytes = 1 << 20
+
+type InvokeRequest struct {
+	OperationID string            `json:"operationId"`
+	Params      map[string]string `json:"params,omitempty"`
+	Body        string            `json:"body,omitempty"`
+	ContentType string            `json:"contentType,omitempty"`
+}
+
+type InvokeResult struct {
+	RequestID  string      `json:"requestId"`
+	Service    string      `json:"service"`
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	Status     int         `json:"status"`
+	Headers    http.Header `json:"headers"`
+	Body       string      `json:"body"`
+	DurationMs float64     `json:"durationMs"`
+}
+
+func (g *Gateway) routeForOperation(operationID string) *route {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	for _, routes := range g.routes {
+		for _, rt := range routes {
+			if operationIDFor(rt.service, rt.endpoint) == operationID {
+				return rt
+			}
+		}
+	}
+	return nil
+}
+
+// Operation returns copies of the service and endpoint that serve operationID.
+func (g *Gateway) Operation(operationID string) (Service, Endpoint, bool) {
+	rt := g.routeForOperation(operationID)
+	if rt == nil {
+		return Service{}, Endpoint{}, false
+	}
+	return *rt.service, rt.endpoint, true
+}
+
+// Invoke calls an operation through the proxy path, as the console and the
+// call command do. The call is audited with an admin client marker.
+func (g *Gateway) Invoke(ctx context.Context, in InvokeRequest) (*InvokeResult, error) {
+	return g.invoke(ctx, in, "")
+}
+
+func (g *Gateway) invoke(ctx context.Context, in InvokeRequest, remoteAddr string) (*InvokeResult, error) {
+	rt := g.routeForOperation(in.OperationID)
+	if rt == nil {
+		return nil, &StatusError{Code: http.StatusNotFound, Message: fmt.Sprintf("unknown operation %q", in.OperationID)}
+	}
+	for _, p := range rt.endpoint.Parameters {
+		if p.In == "header" && in.Params[p.Name] != "" && !g.forwardsRequestHeader(p.Name) {
+			return nil, &StatusError{Code: http.StatusBadRequest, Message: fmt.Sprintf("header parameter %q is not forwarded to services by the gateway", p.Name)}
+		}
+	}
+	req, err := rt.newInvokeRequest(ctx, in)
+	if err != nil {
+		return nil, &StatusError{Code: http.StatusBadRequest, Message: err.Error()}
+	}
+	if remoteAddr != "" {
+		req.RemoteAddr = remoteAddr
+	}
+
+	info := &requestInfo{requestID: newRequestID(), admin: true}
+	req = req.WithContext(context.WithValue(req.Context(), requestInfoKey{}, info))
+	req.Header.Set(requestIDHeader, info.requestID)
+
+	rec := httptest.NewRecorder()
+	rec.Header().Set(requestIDHeader, info.requestID)
+	proxy := g.AuditMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := g.ProxyRequest(w, r); err != nil {
+			g.writeProxyError(w, r, err)
+		}
+	}))
+	start := time.Now()
+	proxy.ServeHTTP(rec, req)
+	elapsed := time.Since(start)
+	g.recordRecentRequest(req, info, rec.Code, start)
+	return &InvokeResult{
+		RequestID:  info.requestID,
+		Service:    rt.service.Name,
+		Method:     req.Method,
+		URL:        req.URL.RequestURI(),
+		Status:     rec.Code,
+		Headers:    rec.Header(),
+		Body:       rec.Body.String(),
+		DurationMs: float64(elapsed.Microseconds()) / 1000,
+	}, nil
+}
+
+func (rt *route) newInvokeRequest(ctx context.Context, in InvokeRequest) (*http.Request, error) {
+	query := url.Values{}
+	header := http.Header{}
+	declared := make(map[string]bool, len(rt.endpoint.Parameters))
+	for _, p := range rt.endpoint.Parameters {
+		declared[p.Name] = true
+		value, ok := in.Params[p.Name]
+		if !ok || value == "" {
+			if p.Required {
+				return nil, fmt.Errorf("missing required %s parameter %q", p.In, p.Name)
+			}
+			continue
+		}
+		switch p.In {
+		case "query":
+			query.Set(p.Name, value)
+		case "header":
+			header.Set(p.Name, value)
+		case "cookie":
+			header.Add("Cookie", (&http.Cookie{Name: p.Name, Value: value}).String())
+		}
+	}
+	var extra []string
+	for name := range in.Params {
+		if !declared[name] {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		query.Set(name, in.Params[name])
+	}
+
+	target, err := rt.expandPath(in.Params)
+	if err != nil {
+		return nil, err
+	}
+	if encoded := query.Encode(); encoded != "" {
+		target += "?" + encoded
+	}
+
+	var body io.Reader
+	if in.Body != "" {
+		body = strings.NewReader(in.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, rt.endpoint.Method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Host = "localhost"
+	req.RemoteAddr = "127.0.0.1:0"
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if in.Body != "" {
+		contentType := in.ContentType
+		if contentType == "" {
+			contentType = rt.defaultContentType()
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, nil
+}
+
+func (rt *route) defaultContentType() string {
+	if rt.endpoint.RequestBody == nil {
+		return "application/json"
+	}
+	if _, ok := rt.endpoint.RequestBody.Content["application/json"]; ok {
+		return "application/json"
+	}
+	types := make([]string, 0, len(rt.endpoint.RequestBody.Content))
+	for mediaType := range rt.endpoint.RequestBody.Content {
+		types = append(types, mediaType)
+	}
+	sort.Strings(types)
+	if len(types) == 0 || strings.Contains(types[0], "*") {
+		return "application/json"
+	}
+	return types[0]
+}
+
+func (g *Gateway) adminInvoke(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var in InvokeRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxInvokeRequestBytes)).Decode(&in); err != nil {
+		writeError(w, r, http.StatusBadRequest, "invalid invoke request: "+err.Error())
+		return
+	}
+	result, err := g.invoke(r.Context(), in, r.RemoteAddr)
+	if err != nil {
+		g.writeProxyError(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
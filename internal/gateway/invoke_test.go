@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAdminInvokeIsAudited(t *testing.T) {
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer upstream.Close()
+
+	auditPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit, err := NewAuditLogger(AuditConfig{Path: auditPath, MaxBodyBytes: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer audit.Close()
+	cfg := DefaultServerConfig()
+	cfg.Auth.APIKeys = []string{"gateway-key"}
+	g := newTestGateway(t, map[string]string{"items.yaml": testService("items", upstream.URL) + `  - path: /items/{id}
+    method: GET
+    operationId: getItem
+    description: Get an item.
+    parameters:
+      - {name: id, in: path, required: true, schema: {type: string}}
+      - {name: Authorization, in: header, schema: {type: string}}
+`}, WithAuditLog(audit), WithServerConfig(cfg))
+
+	invoke := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/invoke", strings.NewReader(body))
+		req.RemoteAddr = "192.0.2.7:51000"
+		rec := httptest.NewRecorder()
+		g.adminInvoke(rec, req)
+		return rec
+	}
+
+	if rec := invoke(`{"operationId": "addItem", "body": "{\"name\": \"a\"}"}`); rec.Code != http.StatusOK {
+		t.Fatalf("invoke got %d: %s", rec.Code, rec.Body)
+	}
+	data, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record auditRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("audit log %q: %v", data, err)
+	}
+	if !record.Client.Admin || record.Client.RemoteAddr != "192.0.2.7" || record.OperationID != "addItem" || record.Status != http.StatusOK {
+		t.Fatalf("unexpected audit record %+v", record)
+	}
+	if body, _ := record.RequestBody.(map[string]any); body["name"] != "a" {
+		t.Fatalf("request body not recorded: %+v", record.RequestBody)
+	}
+
+	before := calls.Load()
+	rec := invoke(`{"operationId": "getItem", "params": {"id": "1", "Authorization": "Bearer upstream"}}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "Authorization") {
+		t.Fatalf("stripped header parameter got %d: %s", rec.Code, rec.Body)
+	}
+	if calls.Load() != before {
+		t.Fatal("the service was called with a header the gateway strips")
+	}
+}
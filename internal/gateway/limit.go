@@ -0,0 +1,296 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	responseLimitError    = "error"
+	responseLimitTruncate = "truncate"
+
+	defaultMaxRequestBytes  = 1 << 20
+	maxTruncationInputBytes = 32 << 20
+	truncationMarkerField   = "_gateway_truncation"
+)
+
+func (l *ResponseLimit) normalize() error {
+	l.OnExceed = strings.ToLower(strings.TrimSpace(l.OnExceed))
+	if l.MaxBytes < 0 {
+		return fmt.Errorf("maxBytes cannot be negative")
+	}
+	switch l.OnExceed {
+	case "", responseLimitError, responseLimitTruncate:
+	default:
+		return fmt.Errorf("onExceed must be %q or %q (got %q)", responseLimitError, responseLimitTruncate, l.OnExceed)
+	}
+	return nil
+}
+
+func (r *route) responseLimit(defaults ResponseLimit) ResponseLimit {
+	limit := defaults
+	if svc := r.service.Response; svc != nil {
+		if svc.MaxBytes > 0 {
+			limit.MaxBytes = svc.MaxBytes
+		}
+		if svc.OnExceed != "" {
+			limit.OnExceed = svc.OnExceed
+		}
+	}
+	if ep := r.endpoint.Response; ep != nil {
+		if ep.MaxBytes > 0 {
+			limit.MaxBytes = ep.MaxBytes
+		}
+		if ep.OnExceed != "" {
+			limit.OnExceed = ep.OnExceed
+		}
+	}
+	if limit.OnExceed == "" {
+		limit.OnExceed = responseLimitError
+	}
+	return limit
+}
+
+func (g *Gateway) limitResponse(rt *route, resp *http.Response) (io.Reader, error) {
+	limit := rt.responseLimit(g.responseLimitDefault())
+	if limit.MaxBytes == 0 || resp.ContentLength == 0 {
+		return resp.Body, nil
+	}
+	if resp.ContentLength > 0 && resp.ContentLength <= limit.MaxBytes {
+		return resp.Body, nil
+	}
+
+	readLimit := limit.MaxBytes
+	if limit.OnExceed == responseLimitTruncate {
+		readLimit = maxTruncationInputBytes
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, readLimit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) <= limit.MaxBytes {
+		return bytes.NewReader(body), nil
+	}
+
+	operationID := operationIDFor(rt.service, rt.endpoint)
+	tooLarge := &StatusError{
+		Code: http.StatusBadGateway,
+		Message: fmt.Sprintf("response from %s exceeded the gateway limit of %d bytes; request a smaller page or narrower filter",
+			operationID, limit.MaxBytes),
+	}
+	if limit.OnExceed != responseLimitTruncate || int64(len(body)) > readLimit || !isJSONContentType(resp.Header.Get("Content-Type")) {
+		return nil, tooLarge
+	}
+	truncated, ok := truncateJSON(body, limit.MaxBytes)
+	if !ok {
+		return nil, tooLarge
+	}
+	resp.Header.Set("Content-Length", strconv.Itoa(len(truncated)))
+	resp.Header.Set("X-Gateway-Truncated", "true")
+	resp.Header.Del("ETag")
+	return bytes.NewReader(truncated), nil
+}
+
+// truncateJSON shortens the largest array in a JSON document (the document
+// itself or one of the fields of a top-level object) until the encoded result
+// fits in maxBytes, recording what was dropped in a marker field. Top-level
+// arrays are wrapped in an object so the marker has somewhere to live.
+func truncateJSON(body []byte, maxBytes int64) ([]byte, bool) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, false
+	}
+
+	var (
+		obj   map[string]any
+		field string
+		items []any
+	)
+	switch v := doc.(type) {
+	case []any:
+		field = "items"
+		items = v
+		obj = map[string]any{}
+	case map[string]any:
+		obj = v
+		largest := -1
+		for name, value := range v {
+			arr, ok := value.([]any)
+			if !ok {
+				continue
+			}
+			encoded, _ := json.Marshal(arr)
+			if len(encoded) > largest {
+				largest = len(encoded)
+				field = name
+				items = arr
+			}
+		}
+		if field == "" {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+
+	encode := func(kept int) []byte {
+		obj[field] = items[:kept]
+		obj[truncationMarkerField] = map[string]any{
+			"truncated":     true,
+			"field":         field,
+			"returnedItems": kept,
+			"totalItems":    len(items),
+			"message": fmt.Sprintf("The gateway truncated %q to %d of %d items to stay under %d bytes. Request fewer items (for example with paging or filter parameters) to see the rest.",
+				field, kept, len(items), maxBytes),
+		}
+		out, err := json.Marshal(obj)
+		if err != nil {
+			return nil
+		}
+		return out
+	}
+
+	lo, hi := 0, len(items)
+	var best []byte
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		out := encode(mid)
+		if out != nil && int64(len(out)) <= maxBytes {
+			best = out
+			lo = mid + 1
+		} else {
+			hi = mid - 1
+		}
+	}
+	return best, best != nil
+}
+
+func isJSONContentType(value string) bool {
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func (r *route) requestLimit(fallback int64) int64 {
+	if r.endpoint.Request != nil && r.endpoint.Request.MaxBytes > 0 {
+		return r.endpoint.Request.MaxBytes
+	}
+	if r.service.Request != nil && r.service.Request.MaxBytes > 0 {
+		return r.service.Request.MaxBytes
+	}
+	return fallback
+}
+
+func (g *Gateway) checkRequestBody(r *http.Request, rt *route) error {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return nil
+	}
+	maxBytes := rt.requestLimit(g.requestLimitDefault())
+	tooLarge := &StatusError{
+		Code:    http.StatusRequestEntityTooLarge,
+		Message: fmt.Sprintf("request body exceeds the limit of %d bytes", maxBytes),
+	}
+	if maxBytes > 0 && r.ContentLength > maxBytes {
+		return tooLarge
+	}
+	if r.ContentLength < 0 {
+		// Chunked bodies may turn out to be empty; only then is a missing
+		// Content-Type acceptable.
+		var first [1]byte
+		n, err := io.ReadFull(r.Body, first[:])
+		if n == 0 {
+			r.Body.Close()
+			if err != io.EOF {
+				return err
+			}
+			r.Body = http.NoBody
+			r.ContentLength = 0
+			return nil
+		}
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(first[:n]), r.Body), r.Body}
+	}
+	if err := checkRequestContentType(r, rt); err != nil {
+		return err
+	}
+
+	info := requestInfoFrom(r.Context())
+	if maxBytes == 0 {
+		if g.audit != nil && info != nil {
+			if limit := rt.auditRules(g.audit.cfg.MaxBodyBytes).maxBodyBytes; limit > 0 {
+				r.Body = &auditBodyReader{ReadCloser: r.Body, info: info, limit: limit}
+			}
+		}
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBytes+1))
+	r.Body.Close()
+	if err != nil {
+		return err
+	}
+	if int64(len(body)) > maxBytes {
+		return tooLarge
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	if info != nil {
+		info.requestBody = body
+	}
+	if len(body) == 0 {
+		r.Body = http.NoBody
+	}
+	return nil
+}
+
+func checkRequestContentType(r *http.Request, rt *route) error {
+	if rt.endpoint.RequestBody == nil {
+		return nil
+	}
+	contentType := r.Header.Get("Content-Type")
+	if acceptsMediaType(rt.endpoint.RequestBody.Content, contentType) {
+		return nil
+	}
+	declared := make([]string, 0, len(rt.endpoint.RequestBody.Content))
+	for mediaType := range rt.endpoint.RequestBody.Content {
+		declared = append(declared, mediaType)
+	}
+	sort.Strings(declared)
+	return &StatusError{
+		Code:    http.StatusUnsupportedMediaType,
+		Message: fmt.Sprintf("unsupported Content-Type %q; expected one of: %s", contentType, strings.Join(declared, ", ")),
+	}
+}
+
+func acceptsMediaType(declared map[string]MediaTypeDefinition, contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for candidate := range declared {
+		allowed, _, err := mime.ParseMediaType(candidate)
+		if err != nil {
+			continue
+		}
+		if allowed == "*/*" || allowed == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTruncateJSON(t *testing.T) {
+	items := make([]string, 50)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"id":%d,"name":"item"}`, i)
+	}
+	list := "[" + strings.Join(items, ",") + "]"
+
+	tests := []struct {
+		name      string
+		body      string
+		maxBytes  int64
+		ok        bool
+		field     string
+		keepsMeta bool
+	}{
+		{name: "top-level array is wrapped", body: list, maxBytes: 600, ok: true, field: "items"},
+		{name: "largest array field is shortened", body: `{"total":50,"tags":["a","b"],"results":` + list + `}`, maxBytes: 700, ok: true, field: "results", keepsMeta: true},
+		{name: "object without arrays", body: `{"text":"` + strings.Repeat("x", 500) + `"}`, maxBytes: 100},
+		{name: "scalar document", body: `"` + strings.Repeat("x", 500) + `"`, maxBytes: 100},
+		{name: "invalid JSON", body: `{"results": [1, 2,`, maxBytes: 10},
+		{name: "limit below the marker size", body: list, maxBytes: 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, ok := truncateJSON([]byte(tt.body), tt.maxBytes)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if int64(len(out)) > tt.maxBytes {
+				t.Fatalf("output is %d bytes, limit %d", len(out), tt.maxBytes)
+			}
+			var doc map[string]any
+			if err := json.Unmarshal(out, &doc); err != nil {
+				t.Fatalf("output is not valid JSON: %v", err)
+			}
+			marker, _ := doc[truncationMarkerField].(map[string]any)
+			if marker == nil || marker["field"] != tt.field || marker["totalItems"] != float64(50) {
+				t.Fatalf("unexpected marker %v", marker)
+			}
+			kept, _ := doc[tt.field].([]any)
+			if len(kept) == 0 || len(kept) >= 50 || marker["returnedItems"] != float64(len(kept)) {
+				t.Fatalf("kept %d items, marker %v", len(kept), marker)
+			}
+			if tt.keepsMeta && (doc["total"] != float64(50) || len(doc["tags"].([]any)) != 2) {
+				t.Fatalf("other fields changed: %v", doc)
+			}
+		})
+	}
+}
+
+func TestTruncateJSONKeepsLargeNumbers(t *testing.T) {
+	body := `{"id":12345678901234567890,"results":[` + strings.Repeat(`"xxxxxxxxxx",`, 40) + `"x"]}`
+	out, ok := truncateJSON([]byte(body), 400)
+	if !ok {
+		t.Fatal("expected truncation to succeed")
+	}
+	if !strings.Contains(string(out), `"id":12345678901234567890`) {
+		t.Fatalf("large number was not preserved: %s", out)
+	}
+}
+
+// countingReader records whether the gateway read the request body.
+type countingReader struct {
+	io.Reader
+	read int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	c.read += n
+	return n, err
+}
+
+func TestCheckRequestBody(t *testing.T) {
+	var received atomic.Int64
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := io.Copy(io.Discard, r.Body)
+		received.Store(n)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer upstream.Close()
+	cfg := DefaultServerConfig()
+	cfg.Services.RequestLimit.MaxBytes = 32
+	g := newTestGateway(t, map[string]string{"items.yaml": testService("items", upstream.URL)}, WithServerConfig(cfg))
+
+	small := `{"name":"a"}`
+	large := `{"name":"` + strings.Repeat("x", 100) + `"}`
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		chunked     bool
+		want        int
+		bodyRead    bool
+	}{
+		{name: "within the limit", body: small, contentType: "application/json", want: http.StatusCreated, bodyRead: true},
+		{name: "declared length over the limit", body: large, contentType: "application/json", want: http.StatusRequestEntityTooLarge},
+		{name: "chunked body over the limit", body: large, contentType: "application/json", chunked: true, want: http.StatusRequestEntityTooLarge, bodyRead: true},
+		{name: "undeclared content type", body: small, contentType: "text/plain", want: http.StatusUnsupportedMediaType},
+		{name: "missing content type", body: small, want: http.StatusUnsupportedMediaType},
+		{name: "empty chunked body", chunked: true, want: http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received.Store(-1)
+			body := &countingReader{Reader: strings.NewReader(tt.body)}
+			req := httptest.NewRequest(http.MethodPost, "/items", body)
+			req.ContentLength = int64(len(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			g.ProxyHandler(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("got %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if (body.read > 0) != tt.bodyRead {
+				t.Fatalf("read %d body bytes", body.read)
+			}
+			if tt.want == http.StatusCreated && received.Load() != int64(len(tt.body)) {
+				t.Fatalf("service received %d bytes, want %d", received.Load(), len(tt.body))
+			}
+			if tt.want != http.StatusCreated && received.Load() != -1 {
+				t.Fatal("rejected request reached the service")
+			}
+		})
+	}
+}
+
+func TestCheckRequestBodyStreamsWithoutLimit(t *testing.T) {
+	started := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var first [1]byte
+		if _, err := io.ReadFull(r.Body, first[:]); err == nil {
+			close(started)
+		}
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer upstream.Close()
+	cfg := DefaultServerConfig()
+	cfg.Services.RequestLimit.MaxBytes = 0
+	g := newTestGateway(t, map[string]string{"items.yaml": testService("items", upstream.URL)}, WithServerConfig(cfg))
+
+	pr, pw := io.Pipe()
+	req := httptest.NewRequest(http.MethodPost, "/items", pr)
+	req.ContentLength = -1
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.ProxyHandler(rec, req)
+	}()
+
+	pw.Write([]byte(`{"name":"`))
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the service did not receive the body before it was complete")
+	}
+	pw.Write([]byte(`a"}`))
+	pw.Close()
+	<-done
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("got %d: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,256 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	gptMaxOperations          = 30
+	gptMaxSummaryLength       = 300
+	gptMaxDescriptionLength   = 300
+	gptMaxParamDescriptionLen = 700
+)
+
+var gptOperationIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+var gptUnsupportedKeywords = []string{
+	"not", "if", "then", "else", "patternProperties", "dependentSchemas", "dependentRequired",
+	"unevaluatedProperties", "unevaluatedItems", "discriminator", "$dynamicRef", "xml",
+}
+
+var gptPoorlySupportedKeywords = []string{"oneOf", "anyOf", "allOf"}
+
+type LintIssue struct {
+	Severity    string `json:"severity"`
+	Service     string `json:"service,omitempty"`
+	OperationID string `json:"operationId,omitempty"`
+	Method      string `json:"method,omitempty"`
+	Path        string `json:"path,omitempty"`
+	Message     string `json:"message"`
+}
+
+type lintOperation struct {
+	path      string
+	method    string
+	operation map[string]any
+}
+
+// LintOpenAPISpec checks a generated document against the limits of the
+// ChatGPT action importer. Errors are rejected by the importer, warnings are
+// accepted but tend to confuse the model or get truncated.
+func LintOpenAPISpec(spec []byte) ([]LintIssue, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	var issues []LintIssue
+	global := func(severity, format string, args ...any) {
+		issues = append(issues, LintIssue{Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	servers, _ := doc["servers"].([]any)
+	switch len(servers) {
+	case 0:
+		global(SeverityError, "the document has no servers entry; ChatGPT needs exactly one server URL")
+	case 1:
+		server, _ := servers[0].(map[string]any)
+		raw, _ := server["url"].(string)
+		lintServerURL(raw, global)
+	default:
+		global(SeverityError, "the document lists %d servers; ChatGPT uses a single server URL", len(servers))
+	}
+
+	var ops []lintOperation
+	paths, _ := doc["paths"].(map[string]any)
+	for path, item := range paths {
+		methods, _ := item.(map[string]any)
+		for method, op := range methods {
+			if operation, ok := op.(map[string]any); ok {
+				ops = append(ops, lintOperation{path: path, method: strings.ToUpper(method), operation: operation})
+			}
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].path == ops[j].path {
+			return ops[i].method < ops[j].method
+		}
+		return ops[i].path < ops[j].path
+	})
+	if len(ops) == 0 {
+		global(SeverityWarning, "the document has no operations; the GPT will not be able to call anything")
+	}
+	if len(ops) > gptMaxOperations {
+		global(SeverityError, "the document has %d operations; ChatGPT accepts at most %d per action, so disable or split services", len(ops), gptMaxOperations)
+	}
+
+	seenIDs := make(map[string]string)
+	for _, op := range ops {
+		issues = append(issues, lintOperationRules(op, seenIDs)...)
+	}
+	return issues, nil
+}
+
+func lintServerURL(raw string, report func(severity, format string, args ...any)) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		report(SeverityError, "server URL %q is not an absolute URL; ChatGPT needs the public https URL of the gateway", raw)
+		return
+	}
+	if u.Scheme != "https" {
+		report(SeverityError, "server URL %s uses %s; ChatGPT only calls https URLs (put the gateway behind a TLS tunnel or use --tls-cert)", raw, u.Scheme)
+	}
+	host := u.Hostname()
+	if host == "localhost" || strings.HasPrefix(host, "127.") || host == "::1" || strings.HasSuffix(host, ".local") {
+		report(SeverityError, "server URL %s points at this machine; ChatGPT calls actions from the internet and needs a public host", raw)
+	}
+	if strings.Contains(raw, "{") {
+		report(SeverityError, "server URL %s contains variables, which ChatGPT does not substitute", raw)
+	}
+}
+
+func lintOperationRules(op lintOperation, seenIDs map[string]string) []LintIssue {
+	var issues []LintIssue
+	operationID, _ := op.operation["operationId"].(string)
+	service, _ := op.operation["x-service-name"].(string)
+	report := func(severity, format string, args ...any) {
+		issues = append(issues, LintIssue{
+			Severity:    severity,
+			Service:     service,
+			OperationID: operationID,
+			Method:      op.method,
+			Path:        op.path,
+			Message:     fmt.Sprintf(format, args...),
+		})
+	}
+
+	switch {
+	case operationID == "":
+		report(SeverityError, "operation has no operationId; set operationId in the service YAML")
+	case !gptOperationIDPattern.MatchString(operationID):
+		report(SeverityError, "operationId %q must be 1-64 characters of letters, digits, '_' or '-'", operationID)
+	}
+	if prev, ok := seenIDs[operationID]; ok && operationID != "" {
+		report(SeverityError, "operationId %q is also used by %s", operationID, prev)
+	}
+	seenIDs[operationID] = op.method + " " + op.path
+
+	summary, _ := op.operation["summary"].(string)
+	description, _ := op.operation["description"].(string)
+	if summary == "" && description == "" {
+		report(SeverityWarning, "operation has no summary or description; the model will have to guess what it does")
+	}
+	if n := len([]rune(summary)); n > gptMaxSummaryLength {
+		report(SeverityWarning, "summary is %d characters; ChatGPT truncates summaries longer than %d", n, gptMaxSummaryLength)
+	}
+	if n := len([]rune(description)); n > gptMaxDescriptionLength {
+		report(SeverityWarning, "description is %d characters; ChatGPT truncates descriptions longer than %d, shorten the endpoint or service description", n, gptMaxDescriptionLength)
+	}
+
+	params, _ := op.operation["parameters"].([]any)
+	for _, raw := range params {
+		param, _ := raw.(map[string]any)
+		name, _ := param["name"].(string)
+		in, _ := param["in"].(string)
+		if in == "header" || in == "cookie" {
+			report(SeverityWarning, "parameter %q is sent in a %s; ChatGPT only fills path and query parameters", name, in)
+		}
+		if desc, _ := param["description"].(string); len([]rune(desc)) > gptMaxParamDescriptionLen {
+			report(SeverityWarning, "parameter %q description is %d characters; keep it under %d", name, len([]rune(desc)), gptMaxParamDescriptionLen)
+		}
+		if schema, ok := param["schema"].(map[string]any); ok {
+			lintSchemaKeywords(schema, "parameter "+name, report)
+		}
+	}
+
+	if body, ok := op.operation["requestBody"].(map[string]any); ok {
+		content, _ := body["content"].(map[string]any)
+		mediaTypes := make([]string, 0, len(content))
+		for mediaType := range content {
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+		sort.Strings(mediaTypes)
+		if _, ok := content["application/json"]; !ok && len(mediaTypes) > 0 {
+			report(SeverityWarning, "request body accepts %s; ChatGPT sends application/json", strings.Join(mediaTypes, ", "))
+		}
+		for _, mediaType := range mediaTypes {
+			media, _ := content[mediaType].(map[string]any)
+			if schema, ok := media["schema"].(map[string]any); ok {
+				lintSchemaKeywords(schema, "request body", report)
+			}
+		}
+	}
+	return issues
+}
+
+func lintSchemaKeywords(schema map[string]any, where string, report func(severity, format string, args ...any)) {
+	walkSchema(schema, "", func(node map[string]any, pointer string) {
+		location := where
+		if pointer != "" {
+			location += " at " + pointer
+		}
+		for _, keyword := range gptUnsupportedKeywords {
+			if _, ok := node[keyword]; ok {
+				report(SeverityWarning, "%s uses %q, which ChatGPT ignores", location, keyword)
+			}
+		}
+		for _, keyword := range gptPoorlySupportedKeywords {
+			if _, ok := node[keyword]; ok {
+				report(SeverityWarning, "%s uses %q; the model often fills these incorrectly, prefer a single flat schema", location, keyword)
+			}
+		}
+		if ref, ok := node["$ref"].(string); ok && !strings.HasPrefix(ref, "#/") {
+			report(SeverityWarning, "%s references external schema %q, which ChatGPT cannot resolve", location, ref)
+		}
+	})
+}
+
+func walkSchema(schema map[string]any, pointer string, visit func(map[string]any, string)) {
+	visit(schema, pointer)
+	if props, ok := schema["properties"].(map[string]any); ok {
+		names := make([]string, 0, len(props))
+		for name := range props {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if child, ok := props[name].(map[string]any); ok {
+				walkSchema(child, pointer+"/properties/"+name, visit)
+			}
+		}
+	}
+	if items, ok := schema["items"].(map[string]any); ok {
+		walkSchema(items, pointer+"/items", visit)
+	}
+	for _, keyword := range gptPoorlySupportedKeywords {
+		if list, ok := schema[keyword].([]any); ok {
+			for i, item := range list {
+				if child, ok := item.(map[string]any); ok {
+					walkSchema(child, fmt.Sprintf("%s/%s/%d", pointer, keyword, i), visit)
+				}
+			}
+		}
+	}
+}
+
+func (g *Gateway) adminLint(w http.ResponseWriter, r *http.Request) {
+	baseURL := r.URL.Query().Get("baseUrl")
+	if baseURL == "" {
+		baseURL = g.servedBaseURL()
+	}
+	spec, err := g.BuildOpenAPISpec(baseURL)
+	if err == nil {
+		var issues []LintIssue
+		if issues, err = LintOpenAPISpec(spec); err == nil {
+			writeJSON(w, http.StatusOK, map[string]any{"baseUrl": baseURL, "issues": issues})
+			return
+		}
+	}
+	g.loggerFor(r.Context()).Error("failed to lint OpenAPI spec", "error", err)
+	writeError(w, r, http.StatusInternalServerError, "failed to lint OpenAPI spec")
+}
@@ -0,0 +1,124 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+type metrics struct {
+	registry         *prometheus.Registry
+	requests         *prometheus.CounterVec
+	duration         *prometheus.HistogramVec
+	inFlight         prometheus.Gauge
+	upstreamInFlight *prometheus.GaugeVec
+	upstreamErrors   *prometheus.CounterVec
+	services         prometheus.Gauge
+	routes           prometheus.Gauge
+	reloads          *prometheus.CounterVec
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "chatgpt_gateway_requests_total",
+			Help: "HTTP requests handled by the gateway.",
+		}, []string{"service", "operation_id", "method", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "chatgpt_gateway_request_duration_seconds",
+			Help:    "Time spent handling HTTP requests, including the upstream call.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"service", "operation_id", "method", "status"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "chatgpt_gateway_requests_in_flight",
+			Help: "HTTP requests currently being handled by the gateway.",
+		}),
+		upstreamInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "chatgpt_gateway_upstream_requests_in_flight",
+			Help: "Requests currently being proxied to a service.",
+		}, []string{"service", "operation_id"}),
+		upstreamErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "chatgpt_gateway_upstream_errors_total",
+			Help: "Failed upstream calls by reason (transport, timeout or status_5xx).",
+		}, []string{"service", "operation_id", "reason"}),
+		services: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "chatgpt_gateway_services_loaded",
+			Help: "Services currently loaded from the config directory.",
+		}),
+		routes: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "chatgpt_gateway_routes_loaded",
+			Help: "Routes currently served by the gateway.",
+		}),
+		reloads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "chatgpt_gateway_config_reloads_total",
+			Help: "Service definition loads by result (success or failure).",
+		}, []string{"result"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.duration,
+		m.inFlight,
+		m.upstreamInFlight,
+		m.upstreamErrors,
+		m.services,
+		m.routes,
+		m.reloads,
+	)
+	return m
+}
+
+// MetricsHandler serves the Prometheus metrics. When API keys are configured
+// it requires one, since the metrics name every service and operation.
+func (g *Gateway) MetricsHandler() http.Handler {
+	metrics := promhttp.HandlerFor(g.metrics.registry, promhttp.HandlerOpts{Registry: g.metrics.registry})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if g.requireAPIKey(w, r) {
+			metrics.ServeHTTP(w, r)
+		}
+	})
+}
+
+func (g *Gateway) MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		r, info := withRequestInfo(r)
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		g.metrics.inFlight.Inc()
+		defer g.metrics.inFlight.Dec()
+
+		next.ServeHTTP(rec, r)
+
+		elapsed := time.Since(start)
+		status := strconv.Itoa(rec.status)
+		g.metrics.requests.WithLabelValues(info.service, info.operationID, r.Method, status).Inc()
+		g.metrics.duration.WithLabelValues(info.service, info.operationID, r.Method, status).Observe(elapsed.Seconds())
+	})
+}
+
+func (g *Gateway) observeUpstream(rt *route, resp *http.Response, err error) {
+	reason := ""
+	var netErr net.Error
+	switch {
+	case err != nil && errors.Is(err, context.Canceled):
+		return
+	case err != nil && (errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())):
+		reason = "timeout"
+	case err != nil:
+		reason = "transport"
+	case resp.StatusCode >= 500:
+		reason = "status_5xx"
+	default:
+		return
+	}
+	g.metrics.upstreamErrors.WithLabelValues(rt.service.Name, operationIDFor(rt.service, rt.endpoint), reason).Inc()
+}
@@ -0,0 +1,132 @@
+package gateway
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+type requestInfo struct {
+	requestID   string
+	service     string
+	operationID string
+	route       string
+	matched     *route
+	pathParams  map[string]string
+	requestBody []byte
+	admin       bool
+}
+
+type requestInfoKey struct{}
+
+func requestInfoFrom(ctx context.Context) *requestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(*requestInfo)
+	return info
+}
+
+func withRequestInfo(r *http.Request) (*http.Request, *requestInfo) {
+	if info := requestInfoFrom(r.Context()); info != nil {
+		return r, info
+	}
+	info := &requestInfo{}
+	return r.WithContext(context.WithValue(r.Context(), requestInfoKey{}, info)), info
+}
+
+func (rt *route) annotate(r *http.Request) {
+	if info := requestInfoFrom(r.Context()); info != nil {
+		info.service = rt.service.Name
+		info.operationID = operationIDFor(rt.service, rt.endpoint)
+		info.route = rt.endpoint.Path
+		info.matched = rt
+		info.pathParams = rt.pathParams(r.URL.Path)
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(statusCode int) {
+	sr.status = statusCode
+	sr.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (sr *statusRecorder) Flush() {
+	if f, ok := sr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (g *Gateway) LoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		r, info := withRequestInfo(r)
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"duration", time.Since(start),
+		}
+		if info.service != "" {
+			attrs = append(attrs, "service", info.service, "operationId", info.operationID)
+		}
+		level := slog.LevelInfo
+		if rec.status >= 500 {
+			level = slog.LevelWarn
+		}
+		g.loggerFor(r.Context()).Log(r.Context(), level, "http request", attrs...)
+	})
+}
+
+func (g *Gateway) RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r, info := withRequestInfo(r)
+		id := sanitizeRequestID(r.Header.Get(requestIDHeader))
+		if id == "" {
+			id = newRequestID()
+		}
+		info.requestID = id
+		r.Header.Set(requestIDHeader, id)
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (g *Gateway) loggerFor(ctx context.Context) *slog.Logger {
+	if info := requestInfoFrom(ctx); info != nil && info.requestID != "" {
+		return g.logger.With("request_id", info.requestID)
+	}
+	return g.logger
+}
+
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+func sanitizeRequestID(id string) string {
+	id = strings.TrimSpace(id)
+	if id == "" || len(id) > 128 {
+		return ""
+	}
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return ""
+		}
+	}
+	return id
+}
@@ -1,36 +1,61 @@
 package gateway
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
+
+	"gopkg.in/yaml.v3"
 )
 
 func (g *Gateway) BuildOpenAPISpec(baseURL string) ([]byte, error) {
 	g.mu.RLock()
 	defer g.mu.RUnlock()
+	return g.buildOpenAPISpecLocked(baseURL)
+}
+
+func (g *Gateway) buildOpenAPISpecLocked(baseURL string) ([]byte, error) {
+	spec := g.openAPIDocumentLocked(baseURL)
+	if g.openAPIInfo.IncludeLoadErrors {
+		if failed := g.fileStatusesLocked(true); len(failed) > 0 {
+			spec["x-gateway-load-errors"] = failed
+		}
+	}
+	return json.MarshalIndent(spec, "", "  ")
+}
 
+func (g *Gateway) openAPIDocumentLocked(baseURL string) map[string]any {
 	spec := map[string]any{
 		"openapi": "3.1.0",
-		"info": map[string]any{
-			"title":       "Local ChatGPT Gateway",
-			"version":     "1.0.0",
-			"description": "Auto-generated OpenAPI schema for locally discovered MCP servers.",
-		},
+		"info":    g.openAPIInfoLocked(),
 		"servers": []any{
 			map[string]any{"url": baseURL},
 		},
 		"paths": map[string]any{},
 	}
+	if len(g.apiKeys) > 0 {
+		spec["components"] = map[string]any{
+			"securitySchemes": map[string]any{
+				"apiKey": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		}
+		spec["security"] = []any{map[string]any{"apiKey": []any{}}}
+	}
 
-	if len(g.services) > 0 {
-		tags := make([]any, 0, len(g.services))
-		serviceNames := make([]string, 0, len(g.services))
-		for name := range g.services {
+	serviceNames := make([]string, 0, len(g.services))
+	for name, svc := range g.services {
+		if g.serviceEnabled(svc) {
 			serviceNames = append(serviceNames, name)
 		}
-		sort.Strings(serviceNames)
+	}
+	sort.Strings(serviceNames)
+
+	if len(serviceNames) > 0 {
+		tags := make([]any, 0, len(serviceNames))
 		for _, name := range serviceNames {
 			svc := g.services[name]
 			tag := map[string]any{"name": svc.Name}
@@ -44,17 +69,11 @@ func (g *Gateway) BuildOpenAPISpec(baseURL string) ([]byte, error) {
 
 	paths := spec["paths"].(map[string]any)
 
-	serviceNames := make([]string, 0, len(g.services))
-	for name := range g.services {
-		serviceNames = append(serviceNames, name)
-	}
-	sort.Strings(serviceNames)
-
 	for _, name := range serviceNames {
 		svc := g.services[name]
 		for _, ep := range svc.Endpoints {
 			method := strings.ToLower(ep.Method)
-			if method == "" {
+			if method == "" || !g.endpointEnabled(svc, ep) {
 				continue
 			}
 			pathItem, _ := paths[ep.Path].(map[string]any)
@@ -77,12 +96,16 @@ func (g *Gateway) BuildOpenAPISpec(baseURL string) ([]byte, error) {
 				"x-service-name":    svc.Name,
 				"x-service-address": svc.Address,
 			}
-			operationID := ep.OperationID
-			if operationID == "" {
-				operationID = generateOperationID(svc.Name, method, ep.Path)
-			}
-			operation["operationId"] = operationID
+			operation["operationId"] = operationIDFor(svc, ep)
 
+			if ep.ResponseExample != nil {
+				operation["responses"].(map[string]any)["200"] = map[string]any{
+					"description": "Successful response.",
+					"content": map[string]any{
+						"application/json": map[string]any{"example": ep.ResponseExample},
+					},
+				}
+			}
 			if len(ep.Parameters) > 0 {
 				operation["parameters"] = convertParameters(ep.Parameters)
 			}
@@ -92,8 +115,68 @@ func (g *Gateway) BuildOpenAPISpec(baseURL string) ([]byte, error) {
 			pathItem[method] = operation
 		}
 	}
+	return spec
+}
 
-	return json.MarshalIndent(spec, "", "  ")
+func (g *Gateway) openAPIInfoLocked() map[string]any {
+	cfg := g.openAPIInfo
+	version := cfg.Version
+	if version == "" {
+		version = fmt.Sprintf("1.0.%d", max(g.apiVersion, 1))
+	}
+	info := map[string]any{
+		"title":   cfg.Title,
+		"version": version,
+	}
+	if cfg.Description != "" {
+		info["description"] = cfg.Description
+	}
+	if cfg.TermsOfService != "" {
+		info["termsOfService"] = cfg.TermsOfService
+	}
+	if c := cfg.Contact; c != nil {
+		contact := map[string]any{}
+		for key, value := range map[string]string{"name": c.Name, "url": c.URL, "email": c.Email} {
+			if value != "" {
+				contact[key] = value
+			}
+		}
+		info["contact"] = contact
+	}
+	if l := cfg.License; l != nil {
+		license := map[string]any{"name": l.Name}
+		if l.Identifier != "" {
+			license["identifier"] = l.Identifier
+		}
+		if l.URL != "" {
+			license["url"] = l.URL
+		}
+		info["license"] = license
+	}
+	return info
+}
+
+// updateAPIVersionLocked bumps the automatic document version when the set
+// of published operations differs from the one the version was issued for.
+func (g *Gateway) updateAPIVersionLocked() {
+	var ops []string
+	for _, svc := range g.services {
+		for _, ep := range svc.Endpoints {
+			if g.endpointEnabled(svc, ep) {
+				ops = append(ops, strings.ToUpper(ep.Method)+" "+ep.Path+" "+operationIDFor(svc, ep))
+			}
+		}
+	}
+	sort.Strings(ops)
+	sum := sha256.Sum256([]byte(strings.Join(ops, "\n")))
+	number, bumped, err := g.state.apiVersion(hex.EncodeToString(sum[:8]))
+	if err != nil {
+		g.logger.Error("failed to persist OpenAPI version", "error", err)
+	}
+	g.apiVersion = number
+	if bumped {
+		g.logger.Info("operations changed, bumped OpenAPI version", "version", fmt.Sprintf("1.0.%d", number), "operations", len(ops))
+	}
 }
 
 func buildOperationDescription(svc *Service, ep Endpoint) string {
@@ -171,6 +254,13 @@ func convertRequestBody(rb *RequestBody) map[string]any {
 	return body
 }
 
+func operationIDFor(svc *Service, ep Endpoint) string {
+	if ep.OperationID != "" {
+		return ep.OperationID
+	}
+	return generateOperationID(svc.Name, strings.ToLower(ep.Method), ep.Path)
+}
+
 func generateOperationID(serviceName, method, path string) string {
 	sanitized := strings.ReplaceAll(path, "/", "_")
 	sanitized = strings.ReplaceAll(sanitized, "{", "")
@@ -185,3 +275,20 @@ func generateOperationID(serviceName, method, path string) string {
 	}
 	return fmt.Sprintf("%s_%s_%s", serviceName, method, sanitized)
 }
+
+func SpecToYAML(spec []byte) ([]byte, error) {
+	var doc any
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
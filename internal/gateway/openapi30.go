@@ -0,0 +1,235 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// openAPIVersion maps a requested document version ("3.0", "3.1", or a full
+// patch version of either) to the minor version the gateway can emit.
+func openAPIVersion(requested string) (string, bool) {
+	switch {
+	case requested == "":
+		return "3.1", true
+	case requested == "3.0" || strings.HasPrefix(requested, "3.0."):
+		return "3.0", true
+	case requested == "3.1" || strings.HasPrefix(requested, "3.1."):
+		return "3.1", true
+	}
+	return "", false
+}
+
+// ConvertOpenAPISpec returns spec, a generated 3.1 document, in the
+// requested OpenAPI version. Converting to 3.0 rewrites the constructs 3.0
+// lacks: type arrays with "null" become nullable, const becomes a single
+// value enum, schema examples become example and numeric exclusive bounds
+// become boolean ones.
+func ConvertOpenAPISpec(spec []byte, version string) ([]byte, error) {
+	target, ok := openAPIVersion(version)
+	if !ok {
+		return nil, &StatusError{Code: 400, Message: fmt.Sprintf("unsupported OpenAPI version %q (expected 3.0 or 3.1)", version)}
+	}
+	if target == "3.1" {
+		return spec, nil
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, err
+	}
+	downgradeDocument(doc)
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func downgradeDocument(doc map[string]any) {
+	doc["openapi"] = "3.0.3"
+	if info, ok := doc["info"].(map[string]any); ok {
+		delete(info, "summary")
+		if license, ok := info["license"].(map[string]any); ok {
+			delete(license, "identifier")
+		}
+	}
+	delete(doc, "webhooks")
+	delete(doc, "jsonSchemaDialect")
+
+	if components, ok := doc["components"].(map[string]any); ok {
+		if schemas, ok := components["schemas"].(map[string]any); ok {
+			for name, schema := range schemas {
+				schemas[name] = downgradeSchema(schema)
+			}
+		}
+		for _, section := range []string{"parameters", "requestBodies", "responses"} {
+			if items, ok := components[section].(map[string]any); ok {
+				for _, item := range items {
+					downgradeMediaHolder(item)
+				}
+			}
+		}
+	}
+	paths, _ := doc["paths"].(map[string]any)
+	for _, item := range paths {
+		pathItem, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		downgradeParameters(pathItem["parameters"])
+		for _, op := range pathItem {
+			operation, ok := op.(map[string]any)
+			if !ok {
+				continue
+			}
+			downgradeParameters(operation["parameters"])
+			downgradeMediaHolder(operation["requestBody"])
+			if responses, ok := operation["responses"].(map[string]any); ok {
+				for _, response := range responses {
+					downgradeMediaHolder(response)
+				}
+			}
+		}
+	}
+}
+
+func downgradeParameters(v any) {
+	params, _ := v.([]any)
+	for _, p := range params {
+		downgradeMediaHolder(p)
+	}
+}
+
+// downgradeMediaHolder converts the schemas of a parameter, request body or
+// response, which carry them either directly or per media type.
+func downgradeMediaHolder(v any) {
+	holder, ok := v.(map[string]any)
+	if !ok {
+		return
+	}
+	if schema, ok := holder["schema"]; ok {
+		holder["schema"] = downgradeSchema(schema)
+	}
+	content, _ := holder["content"].(map[string]any)
+	for _, media := range content {
+		if m, ok := media.(map[string]any); ok {
+			if schema, ok := m["schema"]; ok {
+				m["schema"] = downgradeSchema(schema)
+			}
+		}
+	}
+}
+
+func downgradeSchema(v any) any {
+	schema, ok := v.(map[string]any)
+	if !ok {
+		return v
+	}
+
+	if types, ok := schema["type"].([]any); ok {
+		var kept []any
+		for _, t := range types {
+			if t == "null" {
+				schema["nullable"] = true
+			} else {
+				kept = append(kept, t)
+			}
+		}
+		switch len(kept) {
+		case 0:
+			delete(schema, "type")
+		case 1:
+			schema["type"] = kept[0]
+		default:
+			delete(schema, "type")
+			alternatives := make([]any, 0, len(kept))
+			for _, t := range kept {
+				alternatives = append(alternatives, map[string]any{"type": t})
+			}
+			// Both the type alternatives and an existing anyOf must hold.
+			if existing, ok := schema["anyOf"]; ok {
+				delete(schema, "anyOf")
+				allOf, _ := schema["allOf"].([]any)
+				schema["allOf"] = append(allOf, map[string]any{"anyOf": existing}, map[string]any{"anyOf": alternatives})
+			} else {
+				schema["anyOf"] = alternatives
+			}
+		}
+	} else if schema["type"] == "null" {
+		delete(schema, "type")
+		schema["nullable"] = true
+	}
+
+	if value, ok := schema["const"]; ok {
+		delete(schema, "const")
+		if _, hasEnum := schema["enum"]; !hasEnum {
+			schema["enum"] = []any{value}
+		}
+	}
+	if enum, ok := schema["enum"].([]any); ok {
+		for _, value := range enum {
+			if value == nil {
+				schema["nullable"] = true
+			}
+		}
+	}
+	if examples, ok := schema["examples"].([]any); ok {
+		delete(schema, "examples")
+		if _, hasExample := schema["example"]; !hasExample && len(examples) > 0 {
+			schema["example"] = examples[0]
+		}
+	}
+	// 3.0 has a single bound per side, so keep whichever of the inclusive
+	// and exclusive bounds is stricter.
+	for _, bound := range []struct {
+		exclusive, inclusive string
+		lower                bool
+	}{
+		{"exclusiveMinimum", "minimum", true},
+		{"exclusiveMaximum", "maximum", false},
+	} {
+		limit, ok := schema[bound.exclusive].(float64)
+		if !ok {
+			continue
+		}
+		inclusive, hasInclusive := schema[bound.inclusive].(float64)
+		if hasInclusive && (bound.lower && inclusive > limit || !bound.lower && inclusive < limit) {
+			delete(schema, bound.exclusive)
+			continue
+		}
+		schema[bound.inclusive] = limit
+		schema[bound.exclusive] = true
+	}
+	for _, keyword := range []string{"$schema", "$id", "$comment", "$defs", "contentMediaType", "contentEncoding", "prefixItems", "patternProperties", "unevaluatedProperties", "dependentRequired", "dependentSchemas", "if", "then", "else"} {
+		delete(schema, keyword)
+	}
+
+	// anyOf/oneOf with a {"type": "null"} branch is the 3.1 spelling of nullable.
+	for _, keyword := range []string{"anyOf", "oneOf"} {
+		branches, ok := schema[keyword].([]any)
+		if !ok {
+			continue
+		}
+		kept := branches[:0]
+		for _, branch := range branches {
+			if b, ok := branch.(map[string]any); ok && len(b) == 1 && b["type"] == "null" {
+				schema["nullable"] = true
+				continue
+			}
+			kept = append(kept, downgradeSchema(branch))
+		}
+		schema[keyword] = kept
+	}
+	if branches, ok := schema["allOf"].([]any); ok {
+		for i, branch := range branches {
+			branches[i] = downgradeSchema(branch)
+		}
+	}
+	for _, keyword := range []string{"items", "not", "additionalProperties"} {
+		if sub, ok := schema[keyword].(map[string]any); ok {
+			schema[keyword] = downgradeSchema(sub)
+		}
+	}
+	if properties, ok := schema["properties"].(map[string]any); ok {
+		for name, property := range properties {
+			properties[name] = downgradeSchema(property)
+		}
+	}
+	return schema
+}
@@ -0,0 +1,144 @@
+package gateway
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDowngradeSchema(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "nullable type array",
+			in:   `{"type": ["string", "null"]}`,
+			want: `{"type": "string", "nullable": true}`,
+		},
+		{
+			name: "null type",
+			in:   `{"type": "null"}`,
+			want: `{"nullable": true}`,
+		},
+		{
+			name: "multiple types",
+			in:   `{"type": ["string", "integer", "null"]}`,
+			want: `{"anyOf": [{"type": "string"}, {"type": "integer"}], "nullable": true}`,
+		},
+		{
+			name: "multiple types with an existing anyOf",
+			in:   `{"type": ["string", "integer"], "anyOf": [{"minLength": 1}, {"minimum": 1}]}`,
+			want: `{"allOf": [{"anyOf": [{"minLength": 1}, {"minimum": 1}]}, {"anyOf": [{"type": "string"}, {"type": "integer"}]}]}`,
+		},
+		{
+			name: "multiple types with an existing anyOf and allOf",
+			in:   `{"type": ["string", "integer"], "anyOf": [{"minLength": 1}], "allOf": [{"description": "id"}]}`,
+			want: `{"allOf": [{"description": "id"}, {"anyOf": [{"minLength": 1}]}, {"anyOf": [{"type": "string"}, {"type": "integer"}]}]}`,
+		},
+		{
+			name: "const",
+			in:   `{"const": "fixed"}`,
+			want: `{"enum": ["fixed"]}`,
+		},
+		{
+			name: "const with enum",
+			in:   `{"const": "a", "enum": ["a", "b"]}`,
+			want: `{"enum": ["a", "b"]}`,
+		},
+		{
+			name: "enum containing null",
+			in:   `{"enum": ["a", null]}`,
+			want: `{"enum": ["a", null], "nullable": true}`,
+		},
+		{
+			name: "examples",
+			in:   `{"type": "string", "examples": ["first", "second"]}`,
+			want: `{"type": "string", "example": "first"}`,
+		},
+		{
+			name: "examples with example",
+			in:   `{"example": "kept", "examples": ["first"]}`,
+			want: `{"example": "kept"}`,
+		},
+		{
+			name: "exclusive bounds",
+			in:   `{"type": "number", "exclusiveMinimum": 0, "exclusiveMaximum": 10}`,
+			want: `{"type": "number", "minimum": 0, "exclusiveMinimum": true, "maximum": 10, "exclusiveMaximum": true}`,
+		},
+		{
+			name: "inclusive bounds are stricter",
+			in:   `{"type": "number", "minimum": 10, "exclusiveMinimum": 5, "maximum": 20, "exclusiveMaximum": 30}`,
+			want: `{"type": "number", "minimum": 10, "maximum": 20}`,
+		},
+		{
+			name: "exclusive bounds are stricter",
+			in:   `{"type": "number", "minimum": 5, "exclusiveMinimum": 10, "maximum": 30, "exclusiveMaximum": 20}`,
+			want: `{"type": "number", "minimum": 10, "exclusiveMinimum": true, "maximum": 20, "exclusiveMaximum": true}`,
+		},
+		{
+			name: "equal bounds",
+			in:   `{"type": "number", "minimum": 5, "exclusiveMinimum": 5}`,
+			want: `{"type": "number", "minimum": 5, "exclusiveMinimum": true}`,
+		},
+		{
+			name: "null branch in anyOf",
+			in:   `{"anyOf": [{"type": "string"}, {"type": "null"}]}`,
+			want: `{"anyOf": [{"type": "string"}], "nullable": true}`,
+		},
+		{
+			name: "null branch in oneOf",
+			in:   `{"oneOf": [{"type": "null"}, {"type": ["integer", "null"]}]}`,
+			want: `{"oneOf": [{"type": "integer", "nullable": true}], "nullable": true}`,
+		},
+		{
+			name: "unsupported keywords",
+			in:   `{"$schema": "https://json-schema.org/draft/2020-12/schema", "type": "array", "prefixItems": [{"type": "string"}]}`,
+			want: `{"type": "array"}`,
+		},
+		{
+			name: "nested schemas",
+			in:   `{"type": "object", "properties": {"tags": {"type": "array", "items": {"type": ["string", "null"]}}}, "additionalProperties": {"const": 1}}`,
+			want: `{"type": "object", "properties": {"tags": {"type": "array", "items": {"type": "string", "nullable": true}}}, "additionalProperties": {"enum": [1]}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var in, want any
+			if err := json.Unmarshal([]byte(tt.in), &in); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			got := downgradeSchema(in)
+			if !reflect.DeepEqual(got, want) {
+				gotJSON, _ := json.Marshal(got)
+				t.Fatalf("got %s\nwant %s", gotJSON, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertOpenAPISpecVersions(t *testing.T) {
+	spec := []byte(`{"openapi": "3.1.0", "info": {"title": "t", "summary": "s"}, "paths": {}}`)
+	same, err := ConvertOpenAPISpec(spec, "3.1")
+	if err != nil || string(same) != string(spec) {
+		t.Fatalf("3.1 should be returned unchanged, got %s, %v", same, err)
+	}
+	out, err := ConvertOpenAPISpec(spec, "3.0.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(out, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["openapi"] != "3.0.3" || doc["info"].(map[string]any)["summary"] != nil {
+		t.Fatalf("unexpected 3.0 document %s", out)
+	}
+	if _, err := ConvertOpenAPISpec(spec, "2.0"); err == nil {
+		t.Fatal("expected an error for an unsupported version")
+	}
+}
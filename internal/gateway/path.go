@@ -2,6 +2,7 @@ package gateway
 
 import (
 	"fmt"
+	"net/url"
 	"strings"
 )
 
@@ -104,3 +105,45 @@ func (r *route) matchPath(requestPath string) (string, bool) {
 	}
 	return "/" + strings.Join(matched, "/"), true
 }
+
+func (r *route) pathParams(requestPath string) map[string]string {
+	trimmed := strings.Trim(requestPath, "/")
+	if trimmed == "" {
+		return nil
+	}
+	parts := strings.Split(trimmed, "/")
+	if len(parts) != len(r.segments) {
+		return nil
+	}
+	var params map[string]string
+	for i, seg := range r.segments {
+		if !seg.isParam {
+			continue
+		}
+		if params == nil {
+			params = make(map[string]string)
+		}
+		value, err := url.PathUnescape(parts[i])
+		if err != nil {
+			value = parts[i]
+		}
+		params[seg.literal] = value
+	}
+	return params
+}
+
+func (r *route) expandPath(params map[string]string) (string, error) {
+	parts := make([]string, len(r.segments))
+	for i, seg := range r.segments {
+		if !seg.isParam {
+			parts[i] = seg.literal
+			continue
+		}
+		value := params[seg.literal]
+		if value == "" {
+			return "", fmt.Errorf("missing path parameter %q", seg.literal)
+		}
+		parts[i] = url.PathEscape(value)
+	}
+	return "/" + strings.Join(parts, "/"), nil
+}
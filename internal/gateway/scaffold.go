@@ -0,0 +1,590 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	scaffoldMaxExampleItems  = 3
+	scaffoldMaxExampleString = 200
+	scaffoldMaxResponseBytes = 1 << 20
+)
+
+var (
+	idSegmentPattern   = regexp.MustCompile(`^(\d+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{24,})$`)
+	httpMethodPattern  = regexp.MustCompile(`^[A-Z]+$`)
+	scaffoldHTTPVerbs  = map[string]string{"GET": "get", "POST": "create", "PUT": "update", "PATCH": "update", "DELETE": "delete"}
+	scaffoldSpecMethod = []string{"get", "post", "put", "patch", "delete"}
+)
+
+type ScaffoldOptions struct {
+	Name        string
+	Address     string
+	Description string
+	// Samples are requests such as "GET /weather/London", "/todos?done=true"
+	// or `POST /todos {"task": "milk"}`. When empty the service's
+	// /openapi.json is used instead.
+	Samples []string
+	Client  *http.Client
+}
+
+type sampleRequest struct {
+	method   string
+	segments []string
+	query    url.Values
+	body     string
+}
+
+// Scaffold builds a starter service definition by probing a running service.
+// The returned notes describe anything that needs a human look.
+func Scaffold(ctx context.Context, opts ScaffoldOptions) (*Service, []string, error) {
+	if opts.Name == "" || opts.Address == "" {
+		return nil, nil, fmt.Errorf("service name and address are required")
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	svc := &Service{
+		Name:        opts.Name,
+		Address:     strings.TrimRight(opts.Address, "/"),
+		Description: opts.Description,
+	}
+	var notes []string
+	if len(opts.Samples) > 0 {
+		endpoints, sampleNotes, err := scaffoldFromSamples(ctx, opts.Client, svc.Address, opts.Samples)
+		if err != nil {
+			return nil, nil, err
+		}
+		svc.Endpoints, notes = endpoints, sampleNotes
+	} else {
+		description, endpoints, specNotes, err := scaffoldFromOpenAPI(ctx, opts.Client, svc.Address)
+		if err != nil {
+			return nil, nil, err
+		}
+		if svc.Description == "" {
+			svc.Description = description
+		}
+		svc.Endpoints, notes = endpoints, specNotes
+	}
+	if len(svc.Endpoints) == 0 {
+		return nil, notes, fmt.Errorf("no endpoints found for %s", svc.Address)
+	}
+	return svc, notes, nil
+}
+
+func MarshalService(svc *Service) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(svc); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	if _, _, err := parseService(svc.Name+".yaml", buf.Bytes()); err != nil {
+		return nil, fmt.Errorf("generated definition does not load: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func scaffoldFromOpenAPI(ctx context.Context, client *http.Client, address string) (string, []Endpoint, []string, error) {
+	status, body, err := scaffoldFetch(ctx, client, http.MethodGet, address+"/openapi.json", "")
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("unable to fetch %s/openapi.json: %w", address, err)
+	}
+	if status != http.StatusOK {
+		return "", nil, nil, fmt.Errorf("%s/openapi.json returned %d; pass sample requests instead", address, status)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return "", nil, nil, fmt.Errorf("%s/openapi.json is not valid JSON: %w", address, err)
+	}
+	components, _ := doc["components"].(map[string]any)
+	resolve := func(v any) any { return resolveLocalRefs(v, components, 0) }
+
+	var description string
+	if info, ok := doc["info"].(map[string]any); ok {
+		description, _ = info["description"].(string)
+		if description == "" {
+			description, _ = info["title"].(string)
+		}
+	}
+
+	var notes []string
+	var endpoints []Endpoint
+	paths, _ := doc["paths"].(map[string]any)
+	pathNames := make([]string, 0, len(paths))
+	for path := range paths {
+		pathNames = append(pathNames, path)
+	}
+	sort.Strings(pathNames)
+	for _, path := range pathNames {
+		if path == "/openapi.json" {
+			continue
+		}
+		item, _ := paths[path].(map[string]any)
+		shared, _ := item["parameters"].([]any)
+		for _, method := range scaffoldSpecMethod {
+			op, ok := item[method].(map[string]any)
+			if !ok {
+				continue
+			}
+			ep := Endpoint{Path: path, Method: strings.ToUpper(method)}
+			ep.OperationID, _ = op["operationId"].(string)
+			ep.Description, _ = op["summary"].(string)
+			if ep.Description == "" {
+				ep.Description, _ = op["description"].(string)
+			}
+			opParams, _ := op["parameters"].([]any)
+			for _, raw := range append(append([]any{}, shared...), opParams...) {
+				param, _ := resolve(raw).(map[string]any)
+				name, _ := param["name"].(string)
+				if name == "" {
+					continue
+				}
+				p := Parameter{Name: name}
+				p.In, _ = param["in"].(string)
+				p.Required, _ = param["required"].(bool)
+				p.Description, _ = param["description"].(string)
+				p.Schema, _ = param["schema"].(map[string]any)
+				ep.Parameters = append(ep.Parameters, p)
+			}
+			if rb, ok := resolve(op["requestBody"]).(map[string]any); ok {
+				body := &RequestBody{Content: make(map[string]MediaTypeDefinition)}
+				body.Description, _ = rb["description"].(string)
+				body.Required, _ = rb["required"].(bool)
+				content, _ := rb["content"].(map[string]any)
+				for mediaType, raw := range content {
+					media, _ := raw.(map[string]any)
+					def := MediaTypeDefinition{Example: media["example"]}
+					def.Schema, _ = media["schema"].(map[string]any)
+					body.Content[mediaType] = def
+				}
+				if len(body.Content) > 0 {
+					ep.RequestBody = body
+				}
+			}
+			ep.ResponseExample = specResponseExample(op)
+			if ep.ResponseExample == nil && ep.Method == http.MethodGet && !hasRequiredParams(ep) {
+				example, note := captureExample(ctx, client, address, ep.Method, ep.Path, "")
+				ep.ResponseExample = example
+				if note != "" {
+					notes = append(notes, note)
+				}
+			}
+			endpoints = append(endpoints, ep)
+		}
+	}
+	return description, endpoints, notes, nil
+}
+
+func specResponseExample(op map[string]any) any {
+	responses, _ := op["responses"].(map[string]any)
+	for _, code := range []string{"200", "201", "default"} {
+		resp, _ := responses[code].(map[string]any)
+		content, _ := resp["content"].(map[string]any)
+		media, _ := content["application/json"].(map[string]any)
+		if example, ok := media["example"]; ok {
+			return trimExample(example)
+		}
+	}
+	return nil
+}
+
+func resolveLocalRefs(v any, components map[string]any, depth int) any {
+	if depth > 8 {
+		return v
+	}
+	switch value := v.(type) {
+	case map[string]any:
+		if ref, ok := value["$ref"].(string); ok && strings.HasPrefix(ref, "#/components/") {
+			var target any = components
+			for _, part := range strings.Split(strings.TrimPrefix(ref, "#/components/"), "/") {
+				m, _ := target.(map[string]any)
+				target = m[part]
+			}
+			if target != nil {
+				return resolveLocalRefs(target, components, depth+1)
+			}
+		}
+		out := make(map[string]any, len(value))
+		for k, child := range value {
+			out[k] = resolveLocalRefs(child, components, depth)
+		}
+		return out
+	case []any:
+		out := make([]any, len(value))
+		for i, child := range value {
+			out[i] = resolveLocalRefs(child, components, depth)
+		}
+		return out
+	}
+	return v
+}
+
+func hasRequiredParams(ep Endpoint) bool {
+	for _, p := range ep.Parameters {
+		if p.Required {
+			return true
+		}
+	}
+	return false
+}
+
+func parseSample(raw string) (sampleRequest, error) {
+	s := sampleRequest{method: http.MethodGet}
+	rest := strings.TrimSpace(raw)
+	if first, remainder, ok := strings.Cut(rest, " "); ok && httpMethodPattern.MatchString(first) {
+		s.method, rest = first, strings.TrimSpace(remainder)
+	} else if httpMethodPattern.MatchString(rest) {
+		return s, fmt.Errorf("sample %q has no path", raw)
+	}
+	target, body, _ := strings.Cut(rest, " ")
+	s.body = strings.TrimSpace(body)
+	u, err := url.Parse(target)
+	if err != nil || !strings.HasPrefix(u.Path, "/") {
+		return s, fmt.Errorf("sample %q must look like \"METHOD /path?query body\"", raw)
+	}
+	s.query = u.Query()
+	if trimmed := strings.Trim(u.Path, "/"); trimmed != "" {
+		s.segments = strings.Split(trimmed, "/")
+	}
+	return s, nil
+}
+
+func scaffoldFromSamples(ctx context.Context, client *http.Client, address string, rawSamples []string) ([]Endpoint, []string, error) {
+	var samples []sampleRequest
+	for _, raw := range rawSamples {
+		s, err := parseSample(raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		samples = append(samples, s)
+	}
+
+	// Segments that vary between samples of the same shape, or that look
+	// like identifiers, become path parameters.
+	type shapeKey struct {
+		method string
+		length int
+		first  string
+	}
+	variable := make(map[shapeKey]map[int]bool)
+	for _, a := range samples {
+		key := shapeKey{a.method, len(a.segments), firstSegment(a.segments)}
+		if variable[key] == nil {
+			variable[key] = make(map[int]bool)
+		}
+		for i, seg := range a.segments {
+			if idSegmentPattern.MatchString(seg) {
+				variable[key][i] = true
+			}
+		}
+		for _, b := range samples {
+			if b.method != a.method || len(b.segments) != len(a.segments) || firstSegment(b.segments) != key.first {
+				continue
+			}
+			for i := 1; i < len(a.segments); i++ {
+				if a.segments[i] != b.segments[i] {
+					variable[key][i] = true
+				}
+			}
+		}
+	}
+
+	var notes []string
+	var endpoints []Endpoint
+	index := make(map[string]int)
+	for _, s := range samples {
+		vars := variable[shapeKey{s.method, len(s.segments), firstSegment(s.segments)}]
+		parts := make([]string, len(s.segments))
+		var params []Parameter
+		used := make(map[string]bool)
+		for i, seg := range s.segments {
+			if !vars[i] {
+				parts[i] = seg
+				continue
+			}
+			name := pathParamName(s.segments, i, used)
+			used[name] = true
+			parts[i] = "{" + name + "}"
+			params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: inferSchema(inferScalar(seg))})
+		}
+		path := "/" + strings.Join(parts, "/")
+		key := s.method + " " + path
+		pos, seen := index[key]
+		if !seen {
+			pos = len(endpoints)
+			index[key] = pos
+			endpoints = append(endpoints, Endpoint{
+				Path:        path,
+				Method:      s.method,
+				OperationID: scaffoldOperationID(s.method, parts),
+				Parameters:  params,
+			})
+		}
+		ep := &endpoints[pos]
+		addQueryParams(ep, s.query)
+		if s.body != "" && ep.RequestBody == nil {
+			ep.RequestBody = requestBodyFromSample(s.body)
+		}
+		if ep.ResponseExample == nil {
+			target := "/" + strings.Join(s.segments, "/")
+			if encoded := s.query.Encode(); encoded != "" {
+				target += "?" + encoded
+			}
+			example, note := captureExample(ctx, client, address, s.method, target, s.body)
+			ep.ResponseExample = example
+			if note != "" {
+				notes = append(notes, note)
+			}
+		}
+	}
+	return endpoints, notes, nil
+}
+
+func firstSegment(segments []string) string {
+	if len(segments) == 0 {
+		return ""
+	}
+	return segments[0]
+}
+
+func pathParamName(segments []string, i int, used map[string]bool) string {
+	base := "param"
+	if i > 0 && !idSegmentPattern.MatchString(segments[i-1]) {
+		base = lowerCamel(singular(segments[i-1]))
+	}
+	suffix := "Name"
+	if idSegmentPattern.MatchString(segments[i]) {
+		suffix = "Id"
+	}
+	name := base + suffix
+	for n := 2; used[name]; n++ {
+		name = fmt.Sprintf("%s%s%d", base, suffix, n)
+	}
+	return name
+}
+
+func addQueryParams(ep *Endpoint, query url.Values) {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		exists := false
+		for _, p := range ep.Parameters {
+			if p.Name == name {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			ep.Parameters = append(ep.Parameters, Parameter{Name: name, In: "query", Schema: inferSchema(inferScalar(query.Get(name)))})
+		}
+	}
+}
+
+func requestBodyFromSample(body string) *RequestBody {
+	var doc any
+	if err := json.Unmarshal([]byte(body), &doc); err != nil {
+		return &RequestBody{Required: true, Content: map[string]MediaTypeDefinition{
+			"text/plain": {Schema: map[string]any{"type": "string"}, Example: body},
+		}}
+	}
+	return &RequestBody{Required: true, Content: map[string]MediaTypeDefinition{
+		"application/json": {Schema: inferSchema(doc), Example: doc},
+	}}
+}
+
+func captureExample(ctx context.Context, client *http.Client, address, method, target, body string) (any, string) {
+	status, data, err := scaffoldFetch(ctx, client, method, address+target, body)
+	if err != nil {
+		return nil, fmt.Sprintf("%s %s: %v (no response example captured)", method, target, err)
+	}
+	if status < 200 || status > 299 {
+		return nil, fmt.Sprintf("%s %s returned %d (no response example captured)", method, target, status)
+	}
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Sprintf("%s %s did not return JSON (no response example captured)", method, target)
+	}
+	return trimExample(doc), ""
+}
+
+func scaffoldFetch(ctx context.Context, client *http.Client, method, target, body string) (int, []byte, error) {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != "" {
+		contentType := "text/plain"
+		if json.Valid([]byte(body)) {
+			contentType = "application/json"
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, scaffoldMaxResponseBytes))
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, data, nil
+}
+
+// trimExample keeps examples short enough to read in YAML and to not bloat
+// the generated OpenAPI document.
+func trimExample(v any) any {
+	switch value := v.(type) {
+	case map[string]any:
+		for k, child := range value {
+			value[k] = trimExample(child)
+		}
+	case []any:
+		if len(value) > scaffoldMaxExampleItems {
+			value = value[:scaffoldMaxExampleItems]
+		}
+		for i, child := range value {
+			value[i] = trimExample(child)
+		}
+		return value
+	case string:
+		if r := []rune(value); len(r) > scaffoldMaxExampleString {
+			return string(r[:scaffoldMaxExampleString]) + "…"
+		}
+	}
+	return v
+}
+
+func inferScalar(s string) any {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return float64(i)
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	if s == "true" || s == "false" {
+		return s == "true"
+	}
+	return s
+}
+
+func inferSchema(v any) map[string]any {
+	switch value := v.(type) {
+	case map[string]any:
+		props := make(map[string]any, len(value))
+		for k, child := range value {
+			props[k] = inferSchema(child)
+		}
+		return map[string]any{"type": "object", "properties": props}
+	case []any:
+		schema := map[string]any{"type": "array"}
+		if len(value) > 0 {
+			schema["items"] = inferSchema(value[0])
+		} else {
+			schema["items"] = map[string]any{"type": "string"}
+		}
+		return schema
+	case float64:
+		if value == float64(int64(value)) {
+			return map[string]any{"type": "integer"}
+		}
+		return map[string]any{"type": "number"}
+	case bool:
+		return map[string]any{"type": "boolean"}
+	default:
+		return map[string]any{"type": "string"}
+	}
+}
+
+func scaffoldOperationID(method string, parts []string) string {
+	verb, ok := scaffoldHTTPVerbs[method]
+	if !ok {
+		verb = strings.ToLower(method)
+	}
+	var b strings.Builder
+	b.WriteString(verb)
+	var params []string
+	for _, part := range parts {
+		if strings.HasPrefix(part, "{") {
+			params = append(params, strings.Trim(part, "{}"))
+			continue
+		}
+		b.WriteString(upperCamel(part))
+	}
+	if len(parts) == 0 {
+		b.WriteString("Root")
+	}
+	for i, param := range params {
+		if i == 0 {
+			b.WriteString("By")
+		} else {
+			b.WriteString("And")
+		}
+		b.WriteString(upperCamel(param))
+	}
+	return b.String()
+}
+
+func singular(word string) string {
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 3:
+		return word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "ss"):
+		return word
+	case strings.HasSuffix(word, "s") && len(word) > 1:
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+func upperCamel(s string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func lowerCamel(s string) string {
+	out := []rune(upperCamel(s))
+	if len(out) == 0 {
+		return "param"
+	}
+	out[0] = unicode.ToLower(out[0])
+	return string(out)
+}
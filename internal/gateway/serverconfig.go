@@ -0,0 +1,387 @@
+package gateway
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ServerConfig is the gateway.yaml server configuration. Only the CORS,
+// auth API keys, log level, OpenAPI info and service request/response limits
+// are applied while running; everything else needs a restart.
+type ServerConfig struct {
+	ServicesDir string          `yaml:"servicesDir,omitempty"`
+	StateFile   string          `yaml:"stateFile,omitempty"`
+	Listen      ListenConfig    `yaml:"listen,omitempty"`
+	Timeouts    TimeoutConfig   `yaml:"timeouts,omitempty"`
+	CORS        CORSConfig      `yaml:"cors,omitempty"`
+	Auth        AuthConfig      `yaml:"auth,omitempty"`
+	Logging     LoggingConfig   `yaml:"logging,omitempty"`
+	OpenAPI     OpenAPIInfo     `yaml:"openapi,omitempty"`
+	Services    ServiceDefaults `yaml:"services,omitempty"`
+}
+
+type ListenConfig struct {
+	Addr      string `yaml:"addr,omitempty"`
+	AdminAddr string `yaml:"adminAddr,omitempty"`
+	TLSCert   string `yaml:"tlsCert,omitempty"`
+	TLSKey    string `yaml:"tlsKey,omitempty"`
+}
+
+type TimeoutConfig struct {
+	ReadHeader time.Duration `yaml:"readHeader,omitempty"`
+	Write      time.Duration `yaml:"write,omitempty"`
+	Idle       time.Duration `yaml:"idle,omitempty"`
+	Shutdown   time.Duration `yaml:"shutdown,omitempty"`
+}
+
+type CORSConfig struct {
+	AllowOrigins  []string `yaml:"allowOrigins,omitempty"`
+	AllowHeaders  []string `yaml:"allowHeaders,omitempty"`
+	AllowMethods  []string `yaml:"allowMethods,omitempty"`
+	ExposeHeaders []string `yaml:"exposeHeaders,omitempty"`
+}
+
+type AuthConfig struct {
+	AdminToken string   `yaml:"adminToken,omitempty"`
+	APIKeys    []string `yaml:"apiKeys,omitempty"`
+}
+
+type LoggingConfig struct {
+	Format string `yaml:"format,omitempty"`
+	Level  string `yaml:"level,omitempty"`
+}
+
+// OpenAPIInfo describes the generated document. An empty Version is filled
+// in automatically and bumped whenever the set of operations changes.
+// ServerURL is the public URL ChatGPT reaches the gateway on; when empty it
+// is derived from each request's Host and X-Forwarded-Proto. SpecVersion
+// selects the OpenAPI version served by default, "3.1" or "3.0".
+// IncludeLoadErrors lists files that failed to load under
+// x-gateway-load-errors.
+type OpenAPIInfo struct {
+	Title          string          `yaml:"title,omitempty"`
+	Version        string          `yaml:"version,omitempty"`
+	Description    string          `yaml:"description,omitempty"`
+	TermsOfService string          `yaml:"termsOfService,omitempty"`
+	Contact        *OpenAPIContact `yaml:"contact,omitempty"`
+	License        *OpenAPILicense `yaml:"license,omitempty"`
+	ServerURL      string          `yaml:"serverUrl,omitempty"`
+	SpecVersion    string          `yaml:"specVersion,omitempty"`
+
+	IncludeLoadErrors bool `yaml:"includeLoadErrors,omitempty"`
+}
+
+type OpenAPIContact struct {
+	Name  string `yaml:"name,omitempty"`
+	URL   string `yaml:"url,omitempty"`
+	Email string `yaml:"email,omitempty"`
+}
+
+type OpenAPILicense struct {
+	Name       string `yaml:"name"`
+	Identifier string `yaml:"identifier,omitempty"`
+	URL        string `yaml:"url,omitempty"`
+}
+
+type ServiceDefaults struct {
+	Timeout       time.Duration `yaml:"timeout,omitempty"`
+	RequestLimit  RequestLimit  `yaml:"requestLimit,omitempty"`
+	ResponseLimit ResponseLimit `yaml:"responseLimit,omitempty"`
+}
+
+func DefaultServerConfig() ServerConfig {
+	return ServerConfig{
+		ServicesDir: filepath.Join(".", "mcp_servers"),
+		StateFile:   "gateway-state.json",
+		Listen:      ListenConfig{Addr: ":8080"},
+		Timeouts: TimeoutConfig{
+			ReadHeader: 10 * time.Second,
+			Write:      90 * time.Second,
+			Idle:       120 * time.Second,
+			Shutdown:   10 * time.Second,
+		},
+		CORS: CORSConfig{
+			AllowOrigins:  []string{"*"},
+			AllowHeaders:  []string{"Content-Type", "Authorization", "X-Requested-With", "X-Request-ID"},
+			AllowMethods:  []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			ExposeHeaders: []string{"X-Request-ID", "X-Cache"},
+		},
+		Logging: LoggingConfig{Format: "text", Level: "info"},
+		OpenAPI: OpenAPIInfo{
+			Title:       "Local ChatGPT Gateway",
+			Description: "Auto-generated OpenAPI schema for locally discovered MCP servers.",
+		},
+		Services: ServiceDefaults{
+			Timeout:      60 * time.Second,
+			RequestLimit: RequestLimit{MaxBytes: defaultMaxRequestBytes},
+		},
+	}
+}
+
+// LoadServerConfig decodes the file at path over cfg, so settings the file
+// leaves out keep their current values. Relative paths in the file are
+// resolved against the file's directory.
+func LoadServerConfig(path string, cfg *ServerConfig) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return yamlLoadError(path, "failed to parse", err)
+	}
+	before := *cfg
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return yamlLoadError(path, "failed to parse", err)
+	}
+
+	baseDir := filepath.Dir(path)
+	for _, field := range []struct{ value, previous *string }{
+		{&cfg.ServicesDir, &before.ServicesDir},
+		{&cfg.StateFile, &before.StateFile},
+		{&cfg.Listen.TLSCert, &before.Listen.TLSCert},
+		{&cfg.Listen.TLSKey, &before.Listen.TLSKey},
+	} {
+		if *field.value != *field.previous {
+			*field.value = resolveFile(baseDir, *field.value)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		loadErr := &LoadError{File: path, Message: "invalid server configuration: " + err.Error()}
+		var fe *fieldError
+		if errors.As(err, &fe) {
+			if node := locateNode(&doc, fe.path); node != nil {
+				loadErr.Line, loadErr.Column = node.Line, node.Column
+			}
+		}
+		return loadErr
+	}
+	return nil
+}
+
+func (c *ServerConfig) Validate() error {
+	if c.ServicesDir == "" {
+		return fieldErrorf([]any{"servicesDir"}, "servicesDir cannot be empty")
+	}
+	if c.Listen.Addr == "" {
+		return fieldErrorf([]any{"listen", "addr"}, "listen.addr cannot be empty")
+	}
+	if (c.Listen.TLSCert == "") != (c.Listen.TLSKey == "") {
+		return fieldErrorf([]any{"listen"}, "listen.tlsCert and listen.tlsKey must be set together")
+	}
+	for key, d := range map[string]time.Duration{
+		"readHeader": c.Timeouts.ReadHeader,
+		"write":      c.Timeouts.Write,
+		"idle":       c.Timeouts.Idle,
+		"shutdown":   c.Timeouts.Shutdown,
+	} {
+		if d < 0 {
+			return fieldErrorf([]any{"timeouts", key}, "timeouts.%s cannot be negative", key)
+		}
+	}
+	for i, origin := range c.CORS.AllowOrigins {
+		if origin != "*" && !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://") {
+			return fieldErrorf([]any{"cors", "allowOrigins", i}, "cors origin %q must be \"*\" or start with http:// or https://", origin)
+		}
+	}
+	for i, key := range c.Auth.APIKeys {
+		if strings.TrimSpace(key) == "" {
+			return fieldErrorf([]any{"auth", "apiKeys", i}, "auth.apiKeys entries cannot be empty")
+		}
+	}
+	switch strings.ToLower(c.Logging.Format) {
+	case "text", "json":
+	default:
+		return fieldErrorf([]any{"logging", "format"}, "unknown log format %q (expected text or json)", c.Logging.Format)
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Logging.Level)); err != nil {
+		return fieldErrorf([]any{"logging", "level"}, "unknown log level %q (expected debug, info, warn or error)", c.Logging.Level)
+	}
+	if err := c.OpenAPI.validate(); err != nil {
+		return err
+	}
+	if c.Services.Timeout < 0 {
+		return fieldErrorf([]any{"services", "timeout"}, "services.timeout cannot be negative")
+	}
+	if c.Services.RequestLimit.MaxBytes < 0 {
+		return fieldErrorf([]any{"services", "requestLimit", "maxBytes"}, "services.requestLimit.maxBytes cannot be negative")
+	}
+	if err := c.Services.ResponseLimit.normalize(); err != nil {
+		return fieldErrorf([]any{"services", "responseLimit"}, "services.responseLimit: %v", err)
+	}
+	return nil
+}
+
+func (info *OpenAPIInfo) validate() error {
+	info.ServerURL = strings.TrimRight(info.ServerURL, "/")
+	if info.Title == "" {
+		return fieldErrorf([]any{"openapi", "title"}, "openapi.title cannot be empty")
+	}
+	if _, ok := openAPIVersion(info.SpecVersion); !ok {
+		return fieldErrorf([]any{"openapi", "specVersion"}, "openapi.specVersion must be 3.0 or 3.1 (got %q)", info.SpecVersion)
+	}
+	urls := map[string]string{
+		"serverUrl":      info.ServerURL,
+		"termsOfService": info.TermsOfService,
+	}
+	if info.Contact != nil {
+		urls["contact.url"] = info.Contact.URL
+		if info.Contact.Email != "" && !strings.Contains(info.Contact.Email, "@") {
+			return fieldErrorf([]any{"openapi", "contact", "email"}, "openapi.contact.email %q is not an email address", info.Contact.Email)
+		}
+	}
+	if info.License != nil {
+		if info.License.Name == "" {
+			return fieldErrorf([]any{"openapi", "license"}, "openapi.license.name is required")
+		}
+		if info.License.Identifier != "" && info.License.URL != "" {
+			return fieldErrorf([]any{"openapi", "license"}, "openapi.license accepts identifier or url, not both")
+		}
+		urls["license.url"] = info.License.URL
+	}
+	for key, value := range urls {
+		if value == "" {
+			continue
+		}
+		if u, err := url.Parse(value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			path := []any{"openapi"}
+			for _, part := range strings.Split(key, ".") {
+				path = append(path, part)
+			}
+			return fieldErrorf(path, "openapi.%s must be an absolute http(s) URL (got %q)", key, value)
+		}
+	}
+	return nil
+}
+
+// WithServerConfig applies the server-level settings the gateway itself
+// uses: CORS, API keys, OpenAPI info and service defaults.
+func WithServerConfig(cfg ServerConfig) Option {
+	return func(g *Gateway) {
+		g.client.Timeout = cfg.Services.Timeout
+		g.applyServerConfigLocked(cfg)
+	}
+}
+
+// ApplyServerConfig updates the settings that are safe to change while
+// requests are being served. The upstream timeout is only read at startup.
+func (g *Gateway) ApplyServerConfig(cfg ServerConfig) {
+	g.mu.Lock()
+	g.applyServerConfigLocked(cfg)
+	g.recordSpecGenerationLocked()
+	g.mu.Unlock()
+	g.events.publish("config", nil)
+}
+
+func (g *Gateway) applyServerConfigLocked(cfg ServerConfig) {
+	g.cors = cfg.CORS
+	g.apiKeys = append([]string(nil), cfg.Auth.APIKeys...)
+	g.openAPIInfo = cfg.OpenAPI
+	g.listen = cfg.Listen
+	g.maxRequestBytes = cfg.Services.RequestLimit.MaxBytes
+	g.responseDefaults = cfg.Services.ResponseLimit
+}
+
+func (g *Gateway) setCORSHeaders(w http.ResponseWriter, r *http.Request) {
+	g.mu.RLock()
+	cors := g.cors
+	g.mu.RUnlock()
+
+	origin := r.Header.Get("Origin")
+	allowed := ""
+	for _, o := range cors.AllowOrigins {
+		if o == "*" {
+			allowed = "*"
+			break
+		}
+		if origin != "" && strings.EqualFold(o, origin) {
+			allowed = origin
+		}
+	}
+	if allowed == "" {
+		return
+	}
+	if allowed != "*" {
+		w.Header().Add("Vary", "Origin")
+	}
+	w.Header().Set("Access-Control-Allow-Origin", allowed)
+	w.Header().Set("Access-Control-Allow-Headers", strings.Join(cors.AllowHeaders, ", "))
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(cors.AllowMethods, ", "))
+	w.Header().Set("Access-Control-Expose-Headers", strings.Join(cors.ExposeHeaders, ", "))
+}
+
+// authorizeAPIKey checks the bearer token ChatGPT sends when the action is
+// configured with API key authentication. No keys means no authentication.
+func (g *Gateway) authorizeAPIKey(r *http.Request) bool {
+	g.mu.RLock()
+	keys := g.apiKeys
+	g.mu.RUnlock()
+	if len(keys) == 0 {
+		return true
+	}
+	provided, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || provided == "" {
+		return false
+	}
+	valid := false
+	for _, key := range keys {
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(key)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// requireAPIKey answers 401 and returns false when the request lacks a
+// configured API key.
+func (g *Gateway) requireAPIKey(w http.ResponseWriter, r *http.Request) bool {
+	if g.authorizeAPIKey(r) {
+		return true
+	}
+	w.Header().Set("WWW-Authenticate", `Bearer realm="chatgpt-gateway"`)
+	writeError(w, r, http.StatusUnauthorized, "missing or invalid API key")
+	return false
+}
+
+// PublicBaseURL returns the configured openapi.serverUrl, or "" when the
+// server URL is derived from requests.
+func (g *Gateway) PublicBaseURL() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.openAPIInfo.ServerURL
+}
+
+// SpecVersion returns the OpenAPI version served when a request does not ask
+// for one.
+func (g *Gateway) SpecVersion() string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.openAPIInfo.SpecVersion
+}
+
+func (g *Gateway) requestLimitDefault() int64 {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.maxRequestBytes
+}
+
+func (g *Gateway) responseLimitDefault() ResponseLimit {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	return g.responseDefaults
+}
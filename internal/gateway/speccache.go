@@ -0,0 +1,153 @@
+package gateway
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxSpecCacheEntries bounds the cache, since without a configured server
+// URL the base URL comes from the request's Host header.
+const maxSpecCacheEntries = 16
+
+type specCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedSpec
+}
+
+// cachedSpec is the generated document for one base URL and OpenAPI
+// version. The YAML rendering is derived from the JSON on first use.
+type cachedSpec struct {
+	generation uint64
+	json       []byte
+	etag       string
+	modified   time.Time
+
+	yamlOnce sync.Once
+	yaml     []byte
+	yamlErr  error
+}
+
+func (s *cachedSpec) yamlBytes() ([]byte, error) {
+	s.yamlOnce.Do(func() {
+		s.yaml, s.yamlErr = SpecToYAML(s.json)
+	})
+	return s.yaml, s.yamlErr
+}
+
+// invalidateSpecCacheLocked marks every cached document as stale. Entries
+// are rebuilt on their next request and keep their Last-Modified time when
+// the content turns out to be unchanged.
+func (g *Gateway) invalidateSpecCacheLocked() {
+	g.specCacheGen++
+}
+
+// requestedSpec returns the document in the version named by the request's
+// version query parameter, falling back to openapi.specVersion.
+func (g *Gateway) requestedSpec(r *http.Request, baseURL string) (*cachedSpec, error) {
+	version := r.URL.Query().Get("version")
+	if version == "" {
+		version = g.SpecVersion()
+	}
+	return g.cachedOpenAPISpec(baseURL, version)
+}
+
+func (g *Gateway) cachedOpenAPISpec(baseURL, version string) (*cachedSpec, error) {
+	target, ok := openAPIVersion(version)
+	if !ok {
+		return nil, &StatusError{Code: 400, Message: fmt.Sprintf("unsupported OpenAPI version %q (expected 3.0 or 3.1)", version)}
+	}
+	key := target + " " + baseURL
+
+	g.mu.RLock()
+	generation := g.specCacheGen
+	g.mu.RUnlock()
+	g.specCache.mu.Lock()
+	previous := g.specCache.entries[key]
+	g.specCache.mu.Unlock()
+	if previous != nil && previous.generation == generation {
+		return previous, nil
+	}
+
+	g.mu.RLock()
+	generation = g.specCacheGen
+	spec, err := g.buildOpenAPISpecLocked(baseURL)
+	g.mu.RUnlock()
+	if err == nil {
+		spec, err = ConvertOpenAPISpec(spec, target)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(spec)
+	entry := &cachedSpec{
+		generation: generation,
+		json:       spec,
+		etag:       hex.EncodeToString(sum[:12]),
+		modified:   time.Now().UTC().Truncate(time.Second),
+	}
+	if previous != nil && previous.etag == entry.etag {
+		entry.modified = previous.modified
+	}
+
+	g.specCache.mu.Lock()
+	defer g.specCache.mu.Unlock()
+	if current := g.specCache.entries[key]; current != nil && current.generation >= generation {
+		return current, nil
+	}
+	if g.specCache.entries == nil || (len(g.specCache.entries) >= maxSpecCacheEntries && g.specCache.entries[key] == nil) {
+		g.specCache.entries = make(map[string]*cachedSpec)
+	}
+	g.specCache.entries[key] = entry
+	return entry, nil
+}
+
+// serveOpenAPI writes the document for the request's base URL as JSON or
+// YAML, answering conditional requests with 304 Not Modified.
+func (g *Gateway) serveOpenAPI(w http.ResponseWriter, r *http.Request, format string) {
+	g.setCORSHeaders(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	baseURL := g.PublicBaseURL()
+	if baseURL == "" {
+		scheme := "http"
+		if forwarded := r.Header.Get("X-Forwarded-Proto"); forwarded != "" {
+			scheme = forwarded
+		} else if r.TLS != nil {
+			scheme = "https"
+		}
+		baseURL = fmt.Sprintf("%s://%s", scheme, r.Host)
+	}
+
+	var payload []byte
+	contentType, etag := "application/json", ""
+	spec, err := g.requestedSpec(r, baseURL)
+	if err == nil {
+		payload, etag = spec.json, spec.etag
+		if format == "yaml" {
+			contentType, etag = "application/yaml", spec.etag+"-yaml"
+			payload, err = spec.yamlBytes()
+		}
+	}
+	if err != nil {
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			writeError(w, r, statusErr.Code, statusErr.Message)
+			return
+		}
+		g.loggerFor(r.Context()).Error("failed to build OpenAPI spec", "error", err)
+		writeError(w, r, http.StatusInternalServerError, "failed to build OpenAPI spec")
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("ETag", `"`+etag+`"`)
+	http.ServeContent(w, r, "", spec.modified, bytes.NewReader(payload))
+}
@@ -0,0 +1,137 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServeOpenAPIKeepsRequestHostOutOfAdminViews(t *testing.T) {
+	g := newTestGateway(t, map[string]string{"items.yaml": testService("items", "http://127.0.0.1:1")})
+
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	req.Host = "attacker.example"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	rec := httptest.NewRecorder()
+	g.OpenAPIHandler(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"https://attacker.example"`) {
+		t.Fatalf("public document should use the request's host, got %d %s", rec.Code, rec.Body)
+	}
+	if got := g.servedBaseURL(); got != "http://localhost:8080" {
+		t.Fatalf("admin base URL = %q, want the listen address", got)
+	}
+
+	cfg := DefaultServerConfig()
+	cfg.OpenAPI.ServerURL = "https://gateway.example"
+	g.ApplyServerConfig(cfg)
+	if got := g.servedBaseURL(); got != "https://gateway.example" {
+		t.Fatalf("admin base URL = %q, want openapi.serverUrl", got)
+	}
+}
+
+func fetchSpec(t *testing.T, g *Gateway, target string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	if strings.HasPrefix(target, "/openapi.yaml") {
+		g.OpenAPIYAMLHandler(rec, req)
+	} else {
+		g.OpenAPIHandler(rec, req)
+	}
+	return rec
+}
+
+func TestServeOpenAPIConditionalRequests(t *testing.T) {
+	g := newTestGateway(t, map[string]string{"items.yaml": testService("items", "http://127.0.0.1:1")})
+
+	first := fetchSpec(t, g, "/openapi.json", nil)
+	etag, modified := first.Header().Get("ETag"), first.Header().Get("Last-Modified")
+	if first.Code != http.StatusOK || etag == "" || modified == "" {
+		t.Fatalf("got %d with ETag %q and Last-Modified %q", first.Code, etag, modified)
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		header http.Header
+		want   int
+	}{
+		{name: "matching ETag", target: "/openapi.json", header: http.Header{"If-None-Match": {etag}}, want: http.StatusNotModified},
+		{name: "unchanged since", target: "/openapi.json", header: http.Header{"If-Modified-Since": {modified}}, want: http.StatusNotModified},
+		{name: "other ETag", target: "/openapi.json", header: http.Header{"If-None-Match": {`"stale"`}}, want: http.StatusOK},
+		{name: "YAML has its own ETag", target: "/openapi.yaml", header: http.Header{"If-None-Match": {etag}}, want: http.StatusOK},
+		{name: "3.0 has its own ETag", target: "/openapi.json?version=3.0", header: http.Header{"If-None-Match": {etag}}, want: http.StatusOK},
+		{name: "unsupported version", target: "/openapi.json?version=2.0", want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := fetchSpec(t, g, tt.target, tt.header)
+			if rec.Code != tt.want {
+				t.Fatalf("got %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Fatalf("304 response has a body: %s", rec.Body)
+			}
+		})
+	}
+
+	yaml := fetchSpec(t, g, "/openapi.yaml", nil)
+	if yaml.Header().Get("Content-Type") != "application/yaml" || !strings.Contains(yaml.Body.String(), "openapi: 3.1.0") {
+		t.Fatalf("unexpected YAML response %q: %s", yaml.Header().Get("Content-Type"), yaml.Body)
+	}
+	if rec := fetchSpec(t, g, "/openapi.yaml", http.Header{"If-None-Match": {yaml.Header().Get("ETag")}}); rec.Code != http.StatusNotModified {
+		t.Fatalf("YAML revalidation got %d", rec.Code)
+	}
+}
+
+func TestOpenAPIVersionBumpsAfterChangesSettle(t *testing.T) {
+	defer func(delay time.Duration) { specSettleDelay = delay }(specSettleDelay)
+	specSettleDelay = 50 * time.Millisecond
+
+	g := newTestGateway(t, map[string]string{"items.yaml": testService("items", "http://127.0.0.1:1")})
+	path := filepath.Join(g.ConfigDir(), "items.yaml")
+	version := func() string {
+		var doc struct {
+			Info struct{ Version string } `json:"info"`
+		}
+		if err := json.Unmarshal(fetchSpec(t, g, "/openapi.json", nil).Body.Bytes(), &doc); err != nil {
+			t.Fatal(err)
+		}
+		return doc.Info.Version
+	}
+	etag := fetchSpec(t, g, "/openapi.json", nil).Header().Get("ETag")
+	if v := version(); v != "1.0.1" {
+		t.Fatalf("initial version %s", v)
+	}
+
+	// A save that briefly removes the file settles on the same operations.
+	g.removeService(path)
+	g.loadService(path)
+	time.Sleep(4 * specSettleDelay)
+	if v := version(); v != "1.0.1" {
+		t.Fatalf("version after an unchanged save is %s", v)
+	}
+	if rec := fetchSpec(t, g, "/openapi.json", http.Header{"If-None-Match": {etag}}); rec.Code != http.StatusNotModified {
+		t.Fatalf("unchanged document got %d", rec.Code)
+	}
+
+	writeTestFile(t, path, strings.Replace(testService("items", "http://127.0.0.1:1"), "/items\n    method: POST", "/items/new\n    method: POST", 1))
+	g.loadService(path)
+	if rec := fetchSpec(t, g, "/openapi.json", http.Header{"If-None-Match": {etag}}); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "/items/new") {
+		t.Fatalf("changed document got %d", rec.Code)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for version() != "1.0.2" {
+		if time.Now().After(deadline) {
+			t.Fatalf("version is still %s after the change settled", version())
+		}
+		time.Sleep(specSettleDelay)
+	}
+}
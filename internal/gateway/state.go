@@ -0,0 +1,215 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+type gatewayState struct {
+	Services   map[string]bool  `json:"services,omitempty"`
+	Endpoints  map[string]bool  `json:"endpoints,omitempty"`
+	APIVersion *apiVersionState `json:"apiVersion,omitempty"`
+}
+
+type apiVersionState struct {
+	Number     int    `json:"number"`
+	Operations string `json:"operations"`
+}
+
+type stateStore struct {
+	path     string
+	readOnly bool
+
+	mu    sync.RWMutex
+	state gatewayState
+}
+
+func WithStateFile(path string) Option {
+	return func(g *Gateway) {
+		g.state = &stateStore{path: path, readOnly: g.state.readOnly}
+	}
+}
+
+// WithReadOnlyState applies the overrides in the state file without ever
+// writing it. Commands that only inspect the configuration use it so the
+// persisted OpenAPI version is advanced by the server alone.
+func WithReadOnlyState() Option {
+	return func(g *Gateway) {
+		g.state.readOnly = true
+	}
+}
+
+func (s *stateStore) load() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read state file: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := json.Unmarshal(data, &s.state); err != nil {
+		return fmt.Errorf("invalid state file %s: %w", s.path, err)
+	}
+	return nil
+}
+
+func (s *stateStore) saveLocked() error {
+	if s.path == "" || s.readOnly {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func endpointKey(service, operationID string) string {
+	return service + "/" + operationID
+}
+
+func (s *stateStore) serviceOverride(service string) (bool, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	enabled, ok := s.state.Services[service]
+	return enabled, ok
+}
+
+func (s *stateStore) endpointOverride(service, operationID string) (bool, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	enabled, ok := s.state.Endpoints[endpointKey(service, operationID)]
+	return enabled, ok
+}
+
+func (s *stateStore) setServiceOverride(service string, enabled *bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.Services = setOverride(s.state.Services, service, enabled)
+	return s.saveLocked()
+}
+
+func (s *stateStore) setEndpointOverride(service, operationID string, enabled *bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.Endpoints = setOverride(s.state.Endpoints, endpointKey(service, operationID), enabled)
+	return s.saveLocked()
+}
+
+// apiVersion returns the automatic document version for a set of
+// operations, bumping it when the set differs from the one last recorded.
+func (s *stateStore) apiVersion(operations string) (number int, bumped bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current := s.state.APIVersion
+	if current != nil && current.Operations == operations {
+		return current.Number, false, nil
+	}
+	next := 1
+	if current != nil {
+		next = current.Number + 1
+	}
+	if s.readOnly {
+		// Report the version the server would publish for these operations.
+		return next, false, nil
+	}
+	s.state.APIVersion = &apiVersionState{Number: next, Operations: operations}
+	return next, current != nil, s.saveLocked()
+}
+
+func setOverride(m map[string]bool, key string, enabled *bool) map[string]bool {
+	if enabled == nil {
+		delete(m, key)
+		return m
+	}
+	if m == nil {
+		m = make(map[string]bool)
+	}
+	m[key] = *enabled
+	return m
+}
+
+func (g *Gateway) serviceEnabled(svc *Service) bool {
+	if enabled, ok := g.state.serviceOverride(svc.Name); ok {
+		return enabled
+	}
+	return svc.Enabled == nil || *svc.Enabled
+}
+
+func (g *Gateway) endpointEnabled(svc *Service, ep Endpoint) bool {
+	if !g.serviceEnabled(svc) {
+		return false
+	}
+	if enabled, ok := g.state.endpointOverride(svc.Name, operationIDFor(svc, ep)); ok {
+		return enabled
+	}
+	return ep.Enabled == nil || *ep.Enabled
+}
+
+func (g *Gateway) SetServiceEnabled(name string, enabled *bool) error {
+	g.mu.RLock()
+	_, ok := g.services[name]
+	g.mu.RUnlock()
+	if !ok {
+		return &StatusError{Code: 404, Message: fmt.Sprintf("unknown service %q", name)}
+	}
+	if err := g.state.setServiceOverride(name, enabled); err != nil {
+		return err
+	}
+	g.logger.Info("service override changed", "service", name, "enabled", formatOverride(enabled))
+	g.mu.Lock()
+	g.recordSpecGenerationLocked()
+	g.mu.Unlock()
+	g.events.publish("config", nil)
+	return nil
+}
+
+func (g *Gateway) SetEndpointEnabled(service, operationID string, enabled *bool) error {
+	g.mu.RLock()
+	svc, ok := g.services[service]
+	found := false
+	if ok {
+		for _, ep := range svc.Endpoints {
+			if operationIDFor(svc, ep) == operationID {
+				found = true
+				break
+			}
+		}
+	}
+	g.mu.RUnlock()
+	if !found {
+		return &StatusError{Code: 404, Message: fmt.Sprintf("unknown endpoint %q in service %q", operationID, service)}
+	}
+	if err := g.state.setEndpointOverride(service, operationID, enabled); err != nil {
+		return err
+	}
+	g.logger.Info("endpoint override changed", "service", service, "operationId", operationID, "enabled", formatOverride(enabled))
+	g.mu.Lock()
+	g.recordSpecGenerationLocked()
+	g.mu.Unlock()
+	g.events.publish("config", nil)
+	return nil
+}
+
+func formatOverride(enabled *bool) string {
+	if enabled == nil {
+		return "reset"
+	}
+	return fmt.Sprint(*enabled)
+}
@@ -0,0 +1,100 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestServiceAndEndpointOverrides(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))
+	defer upstream.Close()
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	files := map[string]string{"items.yaml": testService("items", upstream.URL)}
+	g := newTestGateway(t, files, WithStateFile(stateFile))
+	admin := g.AdminHandler("admin-token")
+
+	toggle := func(path string) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", "Bearer admin-token")
+		rec := httptest.NewRecorder()
+		admin.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	call := func(g *Gateway, method string) (int, string) {
+		t.Helper()
+		req := httptest.NewRequest(method, "/items", strings.NewReader(`{"name":"a"}`))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		g.ProxyHandler(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+	published := func(operationID string) bool {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		g.OpenAPIHandler(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+		return strings.Contains(rec.Body.String(), `"operationId": "`+operationID+`"`)
+	}
+
+	steps := []struct {
+		name       string
+		toggle     string
+		wantToggle int
+		wantGet    int
+		wantPost   int
+	}{
+		{name: "defaults", wantGet: http.StatusOK, wantPost: http.StatusOK},
+		{name: "disable the service", toggle: "/admin/services/items/disable", wantToggle: http.StatusOK, wantGet: http.StatusServiceUnavailable, wantPost: http.StatusServiceUnavailable},
+		{name: "reset the service", toggle: "/admin/services/items/reset", wantToggle: http.StatusOK, wantGet: http.StatusOK, wantPost: http.StatusOK},
+		{name: "disable one endpoint", toggle: "/admin/services/items/endpoints/listItems/disable", wantToggle: http.StatusOK, wantGet: http.StatusServiceUnavailable, wantPost: http.StatusOK},
+		{name: "unknown service", toggle: "/admin/services/missing/disable", wantToggle: http.StatusNotFound, wantGet: http.StatusServiceUnavailable, wantPost: http.StatusOK},
+		{name: "unknown endpoint", toggle: "/admin/services/items/endpoints/missing/enable", wantToggle: http.StatusNotFound, wantGet: http.StatusServiceUnavailable, wantPost: http.StatusOK},
+	}
+	for _, step := range steps {
+		if step.toggle != "" {
+			if got := toggle(step.toggle); got != step.wantToggle {
+				t.Fatalf("%s: toggle got %d, want %d", step.name, got, step.wantToggle)
+			}
+		}
+		getStatus, getBody := call(g, http.MethodGet)
+		postStatus, _ := call(g, http.MethodPost)
+		if getStatus != step.wantGet || postStatus != step.wantPost {
+			t.Fatalf("%s: GET %d (%s), POST %d; want %d and %d", step.name, getStatus, getBody, postStatus, step.wantGet, step.wantPost)
+		}
+		if getStatus == http.StatusServiceUnavailable && !strings.Contains(getBody, "disabled") {
+			t.Fatalf("%s: unexpected 503 body %s", step.name, getBody)
+		}
+		if published("listItems") != (step.wantGet == http.StatusOK) || published("addItem") != (step.wantPost == http.StatusOK) {
+			t.Fatalf("%s: published operations do not match the overrides", step.name)
+		}
+	}
+
+	// Overrides survive a restart, and read-only gateways never write them.
+	restarted := newTestGateway(t, files, WithStateFile(stateFile), WithReadOnlyState())
+	if status, _ := call(restarted, http.MethodGet); status != http.StatusServiceUnavailable {
+		t.Fatalf("override was not restored, GET got %d", status)
+	}
+	before, err := os.ReadFile(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := restarted.SetEndpointEnabled("items", "listItems", nil); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := os.ReadFile(stateFile); string(after) != string(before) {
+		t.Fatal("a read-only gateway wrote the state file")
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/services/items/enable", nil)
+	rec := httptest.NewRecorder()
+	admin.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("toggle without a token got %d", rec.Code)
+	}
+}
@@ -0,0 +1,264 @@
+package gateway
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func (c *TLSConfig) normalize(baseDir string) error {
+	c.CAFile = resolveFile(baseDir, c.CAFile)
+	c.CertFile = resolveFile(baseDir, c.CertFile)
+	c.KeyFile = resolveFile(baseDir, c.KeyFile)
+	c.ServerName = strings.TrimSpace(c.ServerName)
+	c.MinVersion = strings.TrimPrefix(strings.TrimSpace(c.MinVersion), "TLS")
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		return fmt.Errorf("certFile and keyFile must be set together")
+	}
+	if c.MinVersion != "" {
+		if _, ok := tlsVersions[c.MinVersion]; !ok {
+			return fmt.Errorf("unsupported minVersion %q (expected 1.0, 1.1, 1.2 or 1.3)", c.MinVersion)
+		}
+	}
+	return nil
+}
+
+func (c *TLSConfig) files() []string {
+	var files []string
+	for _, f := range []string{c.CAFile, c.CertFile, c.KeyFile} {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+func (c *TLSConfig) build() (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if c.MinVersion != "" {
+		cfg.MinVersion = tlsVersions[c.MinVersion]
+	}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", c.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if c.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+func newServiceClient(svc *Service, timeout time.Duration) (*http.Client, error) {
+	if svc.TLS == nil {
+		return nil, nil
+	}
+	tlsConfig, err := svc.TLS.build()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}, nil
+}
+
+func resolveFile(baseDir, path string) string {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return ""
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	return filepath.Clean(path)
+}
+
+func (s *Service) closeIdleConnections() {
+	if s.client != nil {
+		s.client.CloseIdleConnections()
+	}
+}
+
+func (g *Gateway) setTLSDepsLocked(source string, cfg *TLSConfig) {
+	for file, sources := range g.tlsDeps {
+		delete(sources, source)
+		if len(sources) == 0 {
+			delete(g.tlsDeps, file)
+		}
+	}
+	if cfg == nil {
+		return
+	}
+	for _, file := range cfg.files() {
+		if g.tlsDeps[file] == nil {
+			g.tlsDeps[file] = make(map[string]bool)
+		}
+		g.tlsDeps[file][source] = true
+	}
+	g.watchTLSDirsLocked()
+}
+
+func (g *Gateway) watchTLSDirsLocked() {
+	if g.watcher == nil {
+		return
+	}
+	for file := range g.tlsDeps {
+		dir := filepath.Dir(file)
+		if g.watchedDirs[dir] {
+			continue
+		}
+		if err := g.watcher.Add(dir); err != nil {
+			g.logger.Warn("unable to watch TLS directory", "dir", dir, "error", err)
+			continue
+		}
+		g.watchedDirs[dir] = true
+	}
+}
+
+func (g *Gateway) tlsDependents(path string) []string {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	sources := g.tlsDeps[filepath.Clean(path)]
+	out := make([]string, 0, len(sources))
+	for source := range sources {
+		out = append(out, source)
+	}
+	return out
+}
+
+type CertificateReloader struct {
+	certFile string
+	keyFile  string
+	logger   *slog.Logger
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+func NewCertificateReloader(certFile, keyFile string, logger *slog.Logger) (*CertificateReloader, error) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	r := &CertificateReloader{certFile: certFile, keyFile: keyFile, logger: logger}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *CertificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.checkedAt) < time.Second {
+		return r.cert, nil
+	}
+	r.checkedAt = time.Now()
+	if r.latestModTime().After(r.modTime) {
+		if err := r.reloadLocked(); err != nil {
+			r.logger.Error("failed to reload TLS certificate, keeping previous one", "error", err)
+		} else {
+			r.logger.Info("reloaded TLS certificate", "file", r.certFile)
+		}
+	}
+	return r.cert, nil
+}
+
+func (r *CertificateReloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reloadLocked()
+}
+
+func (r *CertificateReloader) reloadLocked() error {
+	modTime := r.latestModTime()
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("unable to load certificate %s: %w", r.certFile, err)
+	}
+	r.cert = &cert
+	r.modTime = modTime
+	r.checkedAt = time.Now()
+	return nil
+}
+
+func (r *CertificateReloader) latestModTime() time.Time {
+	var latest time.Time
+	for _, f := range []string{r.certFile, r.keyFile} {
+		if info, err := os.Stat(f); err == nil && info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest
+}
+
+func SelfSignedCertificate(hosts []string) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"ChatGPT Gateway (development)"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if h != "" {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
@@ -1,50 +1,129 @@
 package gateway
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 type Service struct {
-	Name        string     `yaml:"serviceName"`
-	Address     string     `yaml:"serviceAddress"`
-	Description string     `yaml:"description"`
-	Endpoints   []Endpoint `yaml:"endpoints"`
-	Source      string     `yaml:"-"`
+	Name        string         `yaml:"serviceName"`
+	Address     string         `yaml:"serviceAddress"`
+	Description string         `yaml:"description,omitempty"`
+	Enabled     *bool          `yaml:"enabled,omitempty"`
+	Endpoints   []Endpoint     `yaml:"endpoints"`
+	Request     *RequestLimit  `yaml:"requestLimit,omitempty"`
+	Response    *ResponseLimit `yaml:"responseLimit,omitempty"`
+	Audit       *AuditRules    `yaml:"audit,omitempty"`
+	TLS         *TLSConfig     `yaml:"tls,omitempty"`
+	Source      string         `yaml:"-"`
+
+	client *http.Client
+}
+
+type TLSConfig struct {
+	CAFile             string `yaml:"caFile,omitempty"`
+	CertFile           string `yaml:"certFile,omitempty"`
+	KeyFile            string `yaml:"keyFile,omitempty"`
+	ServerName         string `yaml:"serverName,omitempty"`
+	MinVersion         string `yaml:"minVersion,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecureSkipVerify,omitempty"`
 }
 
 type Endpoint struct {
-	Path        string       `yaml:"path"`
-	Method      string       `yaml:"method"`
-	Description string       `yaml:"description"`
-	OperationID string       `yaml:"operationId"`
-	Parameters  []Parameter  `yaml:"parameters"`
-	RequestBody *RequestBody `yaml:"requestBody"`
+	Path            string         `yaml:"path"`
+	Method          string         `yaml:"method"`
+	Description     string         `yaml:"description,omitempty"`
+	OperationID     string         `yaml:"operationId,omitempty"`
+	Enabled         *bool          `yaml:"enabled,omitempty"`
+	Parameters      []Parameter    `yaml:"parameters,omitempty"`
+	RequestBody     *RequestBody   `yaml:"requestBody,omitempty"`
+	Cache           *CacheConfig   `yaml:"cache,omitempty"`
+	Request         *RequestLimit  `yaml:"requestLimit,omitempty"`
+	Response        *ResponseLimit `yaml:"responseLimit,omitempty"`
+	Audit           *AuditRules    `yaml:"audit,omitempty"`
+	ResponseExample any            `yaml:"responseExample,omitempty"`
+}
+
+type AuditRules struct {
+	Redact       []string `yaml:"redact,omitempty"`
+	MaxBodyBytes *int     `yaml:"maxBodyBytes,omitempty"`
+}
+
+type RequestLimit struct {
+	MaxBytes int64 `yaml:"maxBytes,omitempty"`
+}
+
+type ResponseLimit struct {
+	MaxBytes int64  `yaml:"maxBytes,omitempty"`
+	OnExceed string `yaml:"onExceed,omitempty"`
+}
+
+type CacheConfig struct {
+	TTL           time.Duration `yaml:"ttl,omitempty"`
+	MaxEntryBytes int64         `yaml:"maxEntryBytes,omitempty"`
 }
 
 type Parameter struct {
 	Name        string         `yaml:"name"`
-	In          string         `yaml:"in"`
-	Required    bool           `yaml:"required"`
-	Description string         `yaml:"description"`
-	Schema      map[string]any `yaml:"schema"`
+	In          string         `yaml:"in,omitempty"`
+	Required    bool           `yaml:"required,omitempty"`
+	Description string         `yaml:"description,omitempty"`
+	Schema      map[string]any `yaml:"schema,omitempty"`
 }
 
 type RequestBody struct {
-	Description string                         `yaml:"description"`
-	Required    bool                           `yaml:"required"`
-	Content     map[string]MediaTypeDefinition `yaml:"content"`
+	Description string                         `yaml:"description,omitempty"`
+	Required    bool                           `yaml:"required,omitempty"`
+	Content     map[string]MediaTypeDefinition `yaml:"content,omitempty"`
 }
 
 type MediaTypeDefinition struct {
-	Schema  map[string]any `yaml:"schema"`
-	Example any            `yaml:"example"`
+	Schema  map[string]any `yaml:"schema,omitempty"`
+	Example any            `yaml:"example,omitempty"`
+}
+
+type LoadError struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *LoadError) Error() string {
+	pos := filepath.Base(e.File)
+	if e.Line > 0 {
+		pos += fmt.Sprintf(":%d", e.Line)
+		if e.Column > 0 {
+			pos += fmt.Sprintf(":%d", e.Column)
+		}
+	}
+	return pos + ": " + e.Message
+}
+
+type fieldError struct {
+	path []any
+	err  error
+}
+
+func (e *fieldError) Error() string { return e.err.Error() }
+
+func (e *fieldError) Unwrap() error { return e.err }
+
+func fieldErrorf(path []any, format string, args ...any) error {
+	return &fieldError{path: path, err: fmt.Errorf(format, args...)}
 }
 
+var yamlLinePattern = regexp.MustCompile(`line (\d+):`)
+
 func LoadService(path string) (*Service, error) {
 	info, err := os.Stat(path)
 	if err != nil {
@@ -57,43 +136,115 @@ func LoadService(path string) (*Service, error) {
 	if err != nil {
 		return nil, err
 	}
+	svc, _, err := parseService(path, data)
+	return svc, err
+}
+
+func parseService(path string, data []byte) (*Service, *yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, yamlLoadError(path, "failed to parse", err)
+	}
 	var svc Service
-	if err := yaml.Unmarshal(data, &svc); err != nil {
-		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	if doc.Kind != 0 {
+		if err := doc.Decode(&svc); err != nil {
+			return nil, nil, yamlLoadError(path, "failed to parse", err)
+		}
 	}
 	svc.Source = path
 	if err := svc.normalizeAndValidate(); err != nil {
-		return nil, fmt.Errorf("invalid service definition %s: %w", filepath.Base(path), err)
+		loadErr := &LoadError{File: path, Message: "invalid service definition: " + err.Error()}
+		var fe *fieldError
+		if errors.As(err, &fe) {
+			if node := locateNode(&doc, fe.path); node != nil {
+				loadErr.Line, loadErr.Column = node.Line, node.Column
+			}
+		}
+		return nil, nil, loadErr
+	}
+	return &svc, &doc, nil
+}
+
+func yamlLoadError(path, prefix string, err error) *LoadError {
+	loadErr := &LoadError{File: path, Message: prefix + ": " + err.Error()}
+	if m := yamlLinePattern.FindStringSubmatch(err.Error()); m != nil {
+		loadErr.Line, _ = strconv.Atoi(m[1])
+	}
+	return loadErr
+}
+
+// locateNode walks a decoded document along path (mapping keys and sequence
+// indexes). When a key is missing it returns the closest existing ancestor so
+// "x is required" errors still point at the enclosing block.
+func locateNode(doc *yaml.Node, path []any) *yaml.Node {
+	if doc == nil || doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil
+	}
+	node := doc.Content[0]
+	for _, step := range path {
+		var next *yaml.Node
+		switch key := step.(type) {
+		case string:
+			if node.Kind == yaml.MappingNode {
+				for i := 0; i+1 < len(node.Content); i += 2 {
+					if node.Content[i].Value == key {
+						next = node.Content[i+1]
+						break
+					}
+				}
+			}
+		case int:
+			if node.Kind == yaml.SequenceNode && key >= 0 && key < len(node.Content) {
+				next = node.Content[key]
+			}
+		}
+		if next == nil {
+			return node
+		}
+		node = next
 	}
-	return &svc, nil
+	return node
 }
 
 func (s *Service) normalizeAndValidate() error {
 	s.Name = strings.TrimSpace(s.Name)
 	if s.Name == "" {
-		return fmt.Errorf("serviceName is required")
+		return fieldErrorf([]any{"serviceName"}, "serviceName is required")
 	}
 	s.Address = strings.TrimSpace(s.Address)
 	if s.Address == "" {
-		return fmt.Errorf("serviceAddress is required")
+		return fieldErrorf([]any{"serviceAddress"}, "serviceAddress is required")
 	}
 	s.Address = strings.TrimRight(s.Address, "/")
 	s.Description = strings.TrimSpace(s.Description)
+	if s.Request != nil && s.Request.MaxBytes < 0 {
+		return fieldErrorf([]any{"requestLimit", "maxBytes"}, "invalid requestLimit: maxBytes cannot be negative")
+	}
+	if s.Response != nil {
+		if err := s.Response.normalize(); err != nil {
+			return fieldErrorf([]any{"responseLimit"}, "invalid responseLimit: %w", err)
+		}
+	}
+	if s.TLS != nil {
+		if err := s.TLS.normalize(filepath.Dir(s.Source)); err != nil {
+			return fieldErrorf([]any{"tls"}, "invalid tls settings: %w", err)
+		}
+	}
 	if len(s.Endpoints) == 0 {
-		return fmt.Errorf("service must define at least one endpoint")
+		return fieldErrorf([]any{"endpoints"}, "service must define at least one endpoint")
 	}
 	for i := range s.Endpoints {
 		ep := &s.Endpoints[i]
 		ep.Path = strings.TrimSpace(ep.Path)
 		if ep.Path == "" {
-			return fmt.Errorf("endpoint %d path is required", i)
+			return fieldErrorf([]any{"endpoints", i, "path"}, "endpoint %d path is required", i)
 		}
 		if !strings.HasPrefix(ep.Path, "/") {
 			ep.Path = "/" + ep.Path
 		}
 		method := strings.ToUpper(strings.TrimSpace(ep.Method))
 		if method == "" {
-			return fmt.Errorf("endpoint %s must define a method", ep.Path)
+			return fieldErrorf([]any{"endpoints", i, "method"}, "endpoint %s must define a method", ep.Path)
 		}
 		ep.Method = method
 		ep.Description = strings.TrimSpace(ep.Description)
@@ -101,7 +252,7 @@ func (s *Service) normalizeAndValidate() error {
 
 		paramsInPath, err := extractPathParamNames(ep.Path)
 		if err != nil {
-			return fmt.Errorf("endpoint %s %s has invalid path: %w", ep.Method, ep.Path, err)
+			return fieldErrorf([]any{"endpoints", i, "path"}, "endpoint %s %s has invalid path: %w", ep.Method, ep.Path, err)
 		}
 		expectedParams := make(map[string]bool, len(paramsInPath))
 		for _, name := range paramsInPath {
@@ -112,7 +263,7 @@ func (s *Service) normalizeAndValidate() error {
 			p := &ep.Parameters[idx]
 			p.Name = strings.TrimSpace(p.Name)
 			if p.Name == "" {
-				return fmt.Errorf("endpoint %s %s has a parameter with an empty name", ep.Method, ep.Path)
+				return fieldErrorf([]any{"endpoints", i, "parameters", idx, "name"}, "endpoint %s %s has a parameter with an empty name", ep.Method, ep.Path)
 			}
 			inValue := strings.ToLower(strings.TrimSpace(p.In))
 			if inValue == "" {
@@ -146,6 +297,27 @@ func (s *Service) normalizeAndValidate() error {
 			}
 		}
 
+		if ep.Cache != nil {
+			if ep.Method != "GET" {
+				return fieldErrorf([]any{"endpoints", i, "cache"}, "endpoint %s %s: cache is only supported for GET endpoints", ep.Method, ep.Path)
+			}
+			if ep.Cache.TTL <= 0 {
+				return fieldErrorf([]any{"endpoints", i, "cache", "ttl"}, "endpoint %s %s: cache ttl must be a positive duration such as 30s", ep.Method, ep.Path)
+			}
+			if ep.Cache.MaxEntryBytes < 0 {
+				return fieldErrorf([]any{"endpoints", i, "cache", "maxEntryBytes"}, "endpoint %s %s: cache maxEntryBytes cannot be negative", ep.Method, ep.Path)
+			}
+		}
+
+		if ep.Request != nil && ep.Request.MaxBytes < 0 {
+			return fieldErrorf([]any{"endpoints", i, "requestLimit", "maxBytes"}, "endpoint %s %s has invalid requestLimit: maxBytes cannot be negative", ep.Method, ep.Path)
+		}
+		if ep.Response != nil {
+			if err := ep.Response.normalize(); err != nil {
+				return fieldErrorf([]any{"endpoints", i, "responseLimit"}, "endpoint %s %s has invalid responseLimit: %w", ep.Method, ep.Path, err)
+			}
+		}
+
 		if ep.RequestBody != nil {
 			ep.RequestBody.Description = strings.TrimSpace(ep.RequestBody.Description)
 			if len(ep.RequestBody.Content) == 0 {
@@ -0,0 +1,370 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+var validMethods = map[string]bool{
+	"GET": true, "POST": true, "PUT": true, "PATCH": true, "DELETE": true, "HEAD": true, "OPTIONS": true,
+}
+
+var validParamLocations = map[string]bool{"path": true, "query": true, "header": true, "cookie": true}
+
+var validSchemaTypes = map[string]bool{
+	"string": true, "number": true, "integer": true, "boolean": true, "array": true, "object": true, "null": true,
+}
+
+type Diagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	pos := d.File
+	if d.Line > 0 {
+		pos += fmt.Sprintf(":%d", d.Line)
+		if d.Column > 0 {
+			pos += fmt.Sprintf(":%d", d.Column)
+		}
+	}
+	return fmt.Sprintf("%s: %s: %s", pos, d.Severity, d.Message)
+}
+
+type validatedFile struct {
+	path string
+	svc  *Service
+	doc  *yaml.Node
+}
+
+type validator struct {
+	diags []Diagnostic
+}
+
+func (v *validator) report(file *validatedFile, path []any, severity, format string, args ...any) {
+	d := Diagnostic{File: file.path, Severity: severity, Message: fmt.Sprintf(format, args...)}
+	if node := locateNode(file.doc, path); node != nil {
+		d.Line, d.Column = node.Line, node.Column
+	}
+	v.diags = append(v.diags, d)
+}
+
+func (v *validator) position(file *validatedFile, path []any) string {
+	pos := file.path
+	if node := locateNode(file.doc, path); node != nil {
+		pos += fmt.Sprintf(":%d", node.Line)
+	}
+	return pos
+}
+
+// ValidateFiles checks service definitions the same way the gateway loads
+// them, then looks for problems that only show up once files are combined.
+// Directories are expanded to the YAML files they contain.
+func ValidateFiles(paths ...string) ([]Diagnostic, error) {
+	files, err := expandServicePaths(paths)
+	if err != nil {
+		return nil, err
+	}
+	v := &validator{}
+	var loaded []*validatedFile
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		svc, doc, err := parseService(path, data)
+		if err != nil {
+			d := Diagnostic{File: path, Severity: SeverityError, Message: err.Error()}
+			var loadErr *LoadError
+			if errors.As(err, &loadErr) {
+				d.Line, d.Column, d.Message = loadErr.Line, loadErr.Column, loadErr.Message
+			}
+			v.diags = append(v.diags, d)
+			continue
+		}
+		file := &validatedFile{path: path, svc: svc, doc: doc}
+		if _, err := newServiceClient(svc, 0); err != nil {
+			v.report(file, []any{"tls"}, SeverityError, "invalid tls settings: %v", err)
+		}
+		v.checkEndpoints(file)
+		loaded = append(loaded, file)
+	}
+	v.checkServiceNames(loaded)
+	v.checkOperationIDs(loaded)
+	v.checkRouteConflicts(loaded)
+
+	sort.SliceStable(v.diags, func(i, j int) bool {
+		a, b := v.diags[i], v.diags[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return v.diags, nil
+}
+
+func expandServicePaths(paths []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			if !seen[path] {
+				seen[path] = true
+				files = append(files, path)
+			}
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			file := filepath.Join(path, entry.Name())
+			if entry.IsDir() || !isYAMLFile(file) || seen[file] {
+				continue
+			}
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+func (v *validator) checkEndpoints(file *validatedFile) {
+	for i, ep := range file.svc.Endpoints {
+		if !validMethods[ep.Method] {
+			v.report(file, []any{"endpoints", i, "method"}, SeverityError, "endpoint %s has unsupported method %q", ep.Path, ep.Method)
+		}
+		for idx, p := range ep.Parameters {
+			paramPath := []any{"endpoints", i, "parameters", idx}
+			if !validParamLocations[p.In] {
+				v.report(file, append(paramPath, "in"), SeverityError, "parameter %q has invalid location %q (expected path, query, header or cookie)", p.Name, p.In)
+			}
+			v.checkSchema(file, append(paramPath, "schema"), p.Schema)
+		}
+		if ep.RequestBody != nil {
+			for mediaType, def := range ep.RequestBody.Content {
+				schemaPath := []any{"endpoints", i, "requestBody", "content", mediaType, "schema"}
+				if def.Schema == nil && def.Example == nil {
+					v.report(file, schemaPath[:5], SeverityWarning, "request body media type %s has neither a schema nor an example", mediaType)
+				}
+				v.checkSchema(file, schemaPath, def.Schema)
+			}
+		}
+	}
+}
+
+func (v *validator) checkSchema(file *validatedFile, path []any, schema map[string]any) {
+	if schema == nil {
+		return
+	}
+	child := func(keys ...any) []any {
+		return append(append([]any{}, path...), keys...)
+	}
+	var types []string
+	switch t := schema["type"].(type) {
+	case nil:
+	case string:
+		types = []string{t}
+	case []any:
+		for _, item := range t {
+			s, ok := item.(string)
+			if !ok {
+				v.report(file, child("type"), SeverityError, "schema type list must contain strings")
+				continue
+			}
+			types = append(types, s)
+		}
+	default:
+		v.report(file, child("type"), SeverityError, "schema type must be a string or a list of strings")
+	}
+	for _, t := range types {
+		if !validSchemaTypes[t] {
+			v.report(file, child("type"), SeverityError, "unknown schema type %q", t)
+		}
+	}
+
+	var propertyNames map[string]bool
+	if raw, ok := schema["properties"]; ok {
+		props, isMap := raw.(map[string]any)
+		if !isMap {
+			v.report(file, child("properties"), SeverityError, "schema properties must be a mapping")
+		}
+		propertyNames = make(map[string]bool, len(props))
+		for name, prop := range props {
+			propertyNames[name] = true
+			propSchema, ok := prop.(map[string]any)
+			if !ok {
+				v.report(file, child("properties", name), SeverityError, "schema for property %q must be a mapping", name)
+				continue
+			}
+			v.checkSchema(file, child("properties", name), propSchema)
+		}
+	}
+	if raw, ok := schema["required"]; ok {
+		required, isList := raw.([]any)
+		if !isList {
+			v.report(file, child("required"), SeverityError, "schema required must be a list of property names")
+		}
+		for idx, item := range required {
+			name, ok := item.(string)
+			switch {
+			case !ok:
+				v.report(file, child("required", idx), SeverityError, "schema required entries must be strings")
+			case propertyNames != nil && !propertyNames[name]:
+				v.report(file, child("required", idx), SeverityWarning, "required property %q is not defined in properties", name)
+			}
+		}
+	}
+	if raw, ok := schema["items"]; ok {
+		items, isMap := raw.(map[string]any)
+		if !isMap {
+			v.report(file, child("items"), SeverityError, "schema items must be a mapping")
+		} else {
+			v.checkSchema(file, child("items"), items)
+		}
+	} else if containsString(types, "array") {
+		v.report(file, path, SeverityWarning, "array schema does not define items")
+	}
+	if raw, ok := schema["enum"]; ok {
+		if list, isList := raw.([]any); !isList || len(list) == 0 {
+			v.report(file, child("enum"), SeverityError, "schema enum must be a non-empty list")
+		}
+	}
+}
+
+func (v *validator) checkServiceNames(files []*validatedFile) {
+	first := make(map[string]*validatedFile)
+	for _, file := range files {
+		name := file.svc.Name
+		if prev, ok := first[name]; ok {
+			v.report(file, []any{"serviceName"}, SeverityError, "duplicate serviceName %q (also defined at %s)", name, v.position(prev, []any{"serviceName"}))
+			continue
+		}
+		first[name] = file
+	}
+}
+
+func (v *validator) checkOperationIDs(files []*validatedFile) {
+	type site struct {
+		file  *validatedFile
+		index int
+	}
+	first := make(map[string]site)
+	for _, file := range files {
+		for i, ep := range file.svc.Endpoints {
+			id := operationIDFor(file.svc, ep)
+			if prev, ok := first[id]; ok {
+				v.report(file, []any{"endpoints", i, "operationId"}, SeverityError, "operationId %q is already used at %s", id, v.position(prev.file, []any{"endpoints", prev.index, "operationId"}))
+				continue
+			}
+			first[id] = site{file: file, index: i}
+		}
+	}
+}
+
+func (v *validator) checkRouteConflicts(files []*validatedFile) {
+	type site struct {
+		file  *validatedFile
+		index int
+		route *route
+	}
+	var seen []site
+	for _, file := range files {
+		for i, ep := range file.svc.Endpoints {
+			rt, err := newRoute(file.svc, ep)
+			if err != nil {
+				continue
+			}
+			for _, prev := range seen {
+				if prev.route.endpoint.Method != ep.Method {
+					continue
+				}
+				identical, overlap := compareRoutes(prev.route, rt)
+				where := v.position(prev.file, []any{"endpoints", prev.index, "path"})
+				if prev.file.svc.Name == file.svc.Name {
+					// Within a service routes are tried in declaration order,
+					// so the earlier route deterministically wins.
+					switch {
+					case shadowsRoute(prev.route, rt):
+						v.report(file, []any{"endpoints", i, "path"}, SeverityError, "route %s %s is unreachable: %s %s at %s is declared earlier and matches every request it would", ep.Method, ep.Path, prev.route.endpoint.Method, prev.route.endpoint.Path, where)
+					case overlap && !shadowsRoute(rt, prev.route):
+						v.report(file, []any{"endpoints", i, "path"}, SeverityWarning, "route %s %s overlaps %s %s at %s; requests matching both go to the earlier route", ep.Method, ep.Path, prev.route.endpoint.Method, prev.route.endpoint.Path, where)
+					}
+					continue
+				}
+				switch {
+				case identical:
+					v.report(file, []any{"endpoints", i, "path"}, SeverityError, "route %s %s conflicts with %s %s at %s", ep.Method, ep.Path, prev.route.endpoint.Method, prev.route.endpoint.Path, where)
+				case overlap:
+					v.report(file, []any{"endpoints", i, "path"}, SeverityWarning, "route %s %s overlaps %s %s at %s; which one handles a request is not guaranteed", ep.Method, ep.Path, prev.route.endpoint.Method, prev.route.endpoint.Path, where)
+				}
+			}
+			seen = append(seen, site{file: file, index: i, route: rt})
+		}
+	}
+}
+
+func compareRoutes(a, b *route) (identical, overlap bool) {
+	if len(a.segments) != len(b.segments) {
+		return false, false
+	}
+	identical = true
+	for i := range a.segments {
+		sa, sb := a.segments[i], b.segments[i]
+		switch {
+		case sa.isParam && sb.isParam:
+		case !sa.isParam && !sb.isParam:
+			if sa.literal != sb.literal {
+				return false, false
+			}
+		default:
+			identical = false
+		}
+	}
+	return identical, !identical
+}
+
+// shadowsRoute reports whether every path matched by later is also matched
+// by earlier.
+func shadowsRoute(earlier, later *route) bool {
+	if len(earlier.segments) != len(later.segments) {
+		return false
+	}
+	for i, seg := range earlier.segments {
+		if !seg.isParam && (later.segments[i].isParam || later.segments[i].literal != seg.literal) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(list []string, want string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, want) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchRenamedServiceFiles(t *testing.T) {
+	g := newTestGateway(t, map[string]string{"items.yaml": testService("items", "http://127.0.0.1:1")})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := g.Watch(ctx); err != nil {
+		t.Fatal(err)
+	}
+	dir := g.ConfigDir()
+	serviceFile := func() string {
+		g.mu.RLock()
+		defer g.mu.RUnlock()
+		for path, name := range g.fileToService {
+			if name == "items" && g.services[name] != nil {
+				return filepath.Base(path)
+			}
+		}
+		return ""
+	}
+	waitFor := func(want string) {
+		t.Helper()
+		deadline := time.Now().Add(3 * time.Second)
+		for serviceFile() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("service file is %q, want %q", serviceFile(), want)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	rename := func(from, to string) {
+		t.Helper()
+		if err := os.Rename(filepath.Join(dir, from), filepath.Join(dir, to)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// An editor saving by renaming the file away and writing a new copy
+	// never takes the service down.
+	rename("items.yaml", "items.yaml~")
+	writeTestFile(t, filepath.Join(dir, "items.yaml"), testService("items", "http://127.0.0.1:2"))
+	for end := time.Now().Add(700 * time.Millisecond); time.Now().Before(end); time.Sleep(10 * time.Millisecond) {
+		if got := serviceFile(); got != "items.yaml" {
+			t.Fatalf("service file is %q during an editor save", got)
+		}
+	}
+	g.mu.RLock()
+	address := g.services["items"].Address
+	g.mu.RUnlock()
+	if address != "http://127.0.0.1:2" {
+		t.Fatalf("service address is %s after the save", address)
+	}
+	os.Remove(filepath.Join(dir, "items.yaml~"))
+
+	// Renaming to another YAML file moves the service without dropping it.
+	rename("items.yaml", "renamed.yaml")
+	for end := time.Now().Add(700 * time.Millisecond); time.Now().Before(end); time.Sleep(10 * time.Millisecond) {
+		if got := serviceFile(); got == "" {
+			t.Fatal("service was removed during a rename")
+		}
+	}
+	waitFor("renamed.yaml")
+
+	rename("renamed.yaml", "renamed.yaml.disabled")
+	waitFor("")
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"chatgpt_go/internal/gateway"
+)
+
+func runLint(args []string) int {
+	fs := newCommandFlags("lint")
+	gwFlags := addGatewayFlags(fs)
+	baseURL := fs.String("base-url", "", "Public URL ChatGPT will use to reach the gateway (https://...)")
+	strict := fs.Bool("strict", false, "Treat warnings as errors")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	gw, err := gwFlags.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lint: %v\n", err)
+		return 2
+	}
+	if *baseURL == "" {
+		*baseURL = gw.PublicBaseURL()
+	}
+	spec, err := gw.BuildOpenAPISpec(*baseURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lint: %v\n", err)
+		return 2
+	}
+	issues, err := gateway.LintOpenAPISpec(spec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "lint: %v\n", err)
+		return 2
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		return a.Path < b.Path
+	})
+	errorCount, warningCount := 0, 0
+	group := "-"
+	for _, issue := range issues {
+		heading := "openapi document"
+		if issue.Service != "" {
+			heading = fmt.Sprintf("%s: %s %s (%s)", issue.Service, issue.Method, issue.Path, issue.OperationID)
+		}
+		if heading != group {
+			fmt.Println(heading)
+			group = heading
+		}
+		fmt.Printf("  %s: %s\n", issue.Severity, issue.Message)
+		if issue.Severity == gateway.SeverityError {
+			errorCount++
+		} else {
+			warningCount++
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%d error(s), %d warning(s)\n", errorCount, warningCount)
+	if errorCount > 0 || (*strict && warningCount > 0) {
+		return 1
+	}
+	return 0
+}
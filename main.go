@@ -2,14 +2,18 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
 	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -18,77 +22,218 @@ import (
 )
 
 func main() {
-	log.SetFlags(log.LstdFlags | log.Lmicroseconds)
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			os.Exit(cmd.run(os.Args[2:]))
+		}
+	}
+	flag.Usage = usage
 
-	defaultConfigDir := filepath.Join(".", "mcp_servers")
-	configDir := envOrDefault("CHATGPT_GATEWAY_CONFIG", defaultConfigDir)
-	addr := resolveListenAddr()
+	flagCfg := gateway.DefaultServerConfig()
+	configFile := envOrDefault("CHATGPT_GATEWAY_CONFIG_FILE", defaultServerConfigFile)
+	devMode := envBool("CHATGPT_GATEWAY_DEV")
+	cacheMaxEntries := 1024
+	var cacheMaxBytes int64 = 64 << 20
+	auditCfg := gateway.AuditConfig{
+		Path:         os.Getenv("CHATGPT_GATEWAY_AUDIT_LOG"),
+		MaxSizeBytes: 100 << 20,
+		MaxAge:       24 * time.Hour,
+		MaxBackups:   30,
+		MaxBodyBytes: 4096,
+	}
 
-	flag.StringVar(&configDir, "config", configDir, "Directory containing MCP server definitions")
-	flag.StringVar(&addr, "addr", addr, "Address for the gateway server (host:port or :port)")
+	flag.StringVar(&configFile, "gateway-config", configFile, "Server configuration file (optional unless set explicitly)")
+	bindServerFlags(flag.CommandLine, &flagCfg)
+	flag.BoolVar(&devMode, "dev", devMode, "Development mode: serve HTTPS with a generated self-signed certificate when no certificate is configured")
+	flag.IntVar(&cacheMaxEntries, "cache-max-entries", cacheMaxEntries, "Maximum number of cached responses (0 disables caching)")
+	flag.Int64Var(&cacheMaxBytes, "cache-max-bytes", cacheMaxBytes, "Maximum total size of cached response bodies in bytes")
+	flag.StringVar(&auditCfg.Path, "audit-log", auditCfg.Path, "Append-only JSON Lines audit log of action invocations (disabled when empty)")
+	flag.Int64Var(&auditCfg.MaxSizeBytes, "audit-max-bytes", auditCfg.MaxSizeBytes, "Rotate the audit log once it exceeds this many bytes (0 disables)")
+	flag.DurationVar(&auditCfg.MaxAge, "audit-max-age", auditCfg.MaxAge, "Rotate the audit log once it is older than this (0 disables)")
+	flag.IntVar(&auditCfg.MaxBackups, "audit-max-backups", auditCfg.MaxBackups, "Rotated audit logs to keep (0 keeps all)")
+	flag.IntVar(&auditCfg.MaxBodyBytes, "audit-body-bytes", auditCfg.MaxBodyBytes, "Maximum request/response body bytes recorded per audit entry (0 omits bodies)")
 	flag.Parse()
 
-	gw, err := gateway.New(configDir)
+	source := newServerConfigSource(configFile, flag.CommandLine)
+	cfg, err := source.resolve()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid server configuration: %v\n", err)
+		os.Exit(2)
+	}
+
+	var logLevel slog.LevelVar
+	logLevel.UnmarshalText([]byte(cfg.Logging.Level))
+	logger := newLogger(cfg.Logging.Format, &logLevel)
+	slog.SetDefault(logger)
+
+	tlsConfig, err := listenerTLSConfig(logger, cfg.Listen.TLSCert, cfg.Listen.TLSKey, devMode)
+	if err != nil {
+		fatal(logger, "failed to configure TLS", err)
+	}
+
+	opts := []gateway.Option{
+		gateway.WithCacheLimits(cacheMaxEntries, cacheMaxBytes),
+		gateway.WithServerConfig(cfg),
+		gateway.WithLogger(logger),
+		gateway.WithStateFile(cfg.StateFile),
+	}
+	if auditCfg.Path != "" {
+		audit, err := gateway.NewAuditLogger(auditCfg)
+		if err != nil {
+			fatal(logger, "failed to open audit log", err)
+		}
+		defer audit.Close()
+		opts = append(opts, gateway.WithAuditLog(audit))
+	}
+
+	gw, err := gateway.New(cfg.ServicesDir, opts...)
 	if err != nil {
-		log.Fatalf("failed to initialise gateway: %v", err)
+		fatal(logger, "failed to initialise gateway", err)
 	}
 
 	if err := gw.LoadExisting(); err != nil {
-		log.Fatalf("failed to load service definitions: %v", err)
+		fatal(logger, "failed to load service definitions", err)
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	shutdownTracing, err := gateway.SetupTracing(ctx, gateway.TracingConfig{
+		Exporter: os.Getenv("CHATGPT_GATEWAY_TRACING"),
+		File:     os.Getenv("CHATGPT_GATEWAY_TRACING_FILE"),
+	})
+	if err != nil {
+		fatal(logger, "failed to configure tracing", err)
+	}
+
 	if err := gw.Watch(ctx); err != nil {
-		log.Fatalf("failed to start config watcher: %v", err)
+		fatal(logger, "failed to start config watcher", err)
+	}
+	current := cfg
+	err = watchServerConfig(ctx, logger, configFile, func() {
+		next, err := source.resolve()
+		if err != nil {
+			logger.Error("gateway config not reloaded", "file", configFile, "error", err)
+			return
+		}
+		if changed := restartRequired(current, next); len(changed) > 0 {
+			logger.Warn("gateway config settings changed that only apply after a restart", "settings", strings.Join(changed, ", "))
+		}
+		gw.ApplyServerConfig(next)
+		logLevel.UnmarshalText([]byte(next.Logging.Level))
+		current = next
+		logger.Info("gateway config reloaded", "file", configFile)
+	})
+	if err != nil {
+		logger.Warn("gateway config changes will not be reloaded", "file", configFile, "error", err)
 	}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/openapi.json", gw.OpenAPIHandler)
+	mux.HandleFunc("/openapi.yaml", gw.OpenAPIYAMLHandler)
+	mux.Handle("/metrics", gw.MetricsHandler())
 	mux.HandleFunc("/", gw.ProxyHandler)
 
 	srv := &http.Server{
-		Addr:              addr,
-		Handler:           loggingMiddleware(mux),
-		ReadHeaderTimeout: 10 * time.Second,
-		WriteTimeout:      90 * time.Second,
-		IdleTimeout:       120 * time.Second,
+		Addr:              cfg.Listen.Addr,
+		Handler:           gw.RequestIDMiddleware(gw.MetricsMiddleware(gw.RecentRequestsMiddleware(gw.TracingMiddleware(gw.LoggingMiddleware(gw.AuditMiddleware(mux)))))),
+		ReadHeaderTimeout: cfg.Timeouts.ReadHeader,
+		WriteTimeout:      cfg.Timeouts.Write,
+		IdleTimeout:       cfg.Timeouts.Idle,
+		TLSConfig:         tlsConfig,
+	}
+
+	var adminSrv *http.Server
+	if adminAddr := cfg.Listen.AdminAddr; adminAddr != "" {
+		adminToken := cfg.Auth.AdminToken
+		if adminToken == "" {
+			adminToken = randomToken()
+			// The token goes to the terminal only, never into the log pipeline.
+			logger.Warn("no admin token configured, generated one for this run and printed it to stderr")
+			fmt.Fprintf(os.Stderr, "admin token: %s\n", adminToken)
+		}
+		adminSrv = &http.Server{
+			Addr:              adminAddr,
+			Handler:           gw.RequestIDMiddleware(gw.LoggingMiddleware(gw.AdminHandler(adminToken))),
+			BaseContext:       func(net.Listener) context.Context { return ctx },
+			ReadHeaderTimeout: cfg.Timeouts.ReadHeader,
+			IdleTimeout:       cfg.Timeouts.Idle,
+			TLSConfig:         tlsConfig,
+		}
+		go func() {
+			logger.Info("admin API listening", "addr", adminAddr, "tls", adminSrv.TLSConfig != nil)
+			if err := serve(adminSrv); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				fatal(logger, "admin server error", err)
+			}
+		}()
 	}
 
 	go func() {
 		services := gw.ServicesSnapshot()
-		if len(services) == 0 {
-			log.Printf("[gateway] no services detected yet. Drop MCP YAML files into %s", gw.ConfigDir())
+		files := gw.FileStatuses()
+		if len(files) == 0 {
+			logger.Info("no services detected yet, drop MCP YAML files into the config dir", "config_dir", gw.ConfigDir())
 		} else {
 			for _, svc := range services {
-				log.Printf("[gateway] service ready: %s -> %s (%d endpoints)", svc.Name, svc.Address, len(svc.Endpoints))
+				logger.Info("service ready", "service", svc.Name, "address", svc.Address, "endpoints", len(svc.Endpoints))
+			}
+			failed := 0
+			for _, file := range files {
+				if file.Status == "ok" {
+					continue
+				}
+				failed++
+				logger.Error("service file not loaded", "file", file.File, "line", file.Line, "column", file.Column, "error", file.Error)
 			}
+			logger.Info("service files loaded", "ok", len(files)-failed, "failed", failed)
 		}
-		log.Printf("[gateway] listening on %s (config dir: %s)", addr, gw.ConfigDir())
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Fatalf("server error: %v", err)
+		logger.Info("listening", "addr", cfg.Listen.Addr, "tls", srv.TLSConfig != nil, "config_dir", gw.ConfigDir())
+		if err := serve(srv); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal(logger, "server error", err)
 		}
 	}()
 
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	sig := <-sigCh
-	log.Printf("[gateway] received signal %s, shutting down", sig)
+	logger.Info("received signal, shutting down", "signal", sig.String())
 
-	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.Timeouts.Shutdown)
 	defer shutdownCancel()
 	cancel()
 
+	if adminSrv != nil {
+		if err := adminSrv.Shutdown(shutdownCtx); err != nil {
+			logger.Warn("admin server shutdown failed", "error", err)
+		}
+	}
 	if err := srv.Shutdown(shutdownCtx); err != nil {
 		if errors.Is(err, context.Canceled) {
-			log.Printf("[gateway] shutdown cancelled: %v", err)
+			logger.Warn("shutdown cancelled", "error", err)
 		} else {
-			log.Printf("[gateway] graceful shutdown failed: %v", err)
+			logger.Error("graceful shutdown failed", "error", err)
 		}
 	} else {
-		log.Printf("[gateway] shutdown complete")
+		logger.Info("shutdown complete")
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("failed to flush traces", "error", err)
+	}
+}
+
+func serve(srv *http.Server) error {
+	if srv.TLSConfig != nil {
+		return srv.ListenAndServeTLS("", "")
 	}
+	return srv.ListenAndServe()
+}
+
+func randomToken() string {
+	var b [24]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b[:])
 }
 
 func envOrDefault(key, fallback string) string {
@@ -98,33 +243,50 @@ func envOrDefault(key, fallback string) string {
 	return fallback
 }
 
-func resolveListenAddr() string {
-	if addr := os.Getenv("CHATGPT_GATEWAY_ADDR"); addr != "" {
-		return addr
-	}
-	port := envOrDefault("CHATGPT_GATEWAY_PORT", "8080")
-	if !strings.Contains(port, ":") {
-		return fmt.Sprintf(":%s", port)
-	}
-	return port
+func envBool(key string) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	return err == nil && value
 }
 
-type loggingResponseWriter struct {
-	http.ResponseWriter
-	status int
+func listenerTLSConfig(logger *slog.Logger, certFile, keyFile string, devMode bool) (*tls.Config, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("--tls-cert and --tls-key must be provided together")
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	switch {
+	case certFile != "":
+		reloader, err := gateway.NewCertificateReloader(certFile, keyFile, logger)
+		if err != nil {
+			return nil, err
+		}
+		cfg.GetCertificate = reloader.GetCertificate
+	case devMode:
+		hosts := []string{"localhost", "127.0.0.1", "::1"}
+		if hostname, err := os.Hostname(); err == nil {
+			hosts = append(hosts, hostname)
+		}
+		cert, err := gateway.SelfSignedCertificate(hosts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to generate self-signed certificate: %w", err)
+		}
+		logger.Warn("dev mode: serving a self-signed certificate", "hosts", strings.Join(hosts, ", "))
+		cfg.Certificates = []tls.Certificate{cert}
+	default:
+		return nil, nil
+	}
+	return cfg, nil
 }
 
-func (lrw *loggingResponseWriter) WriteHeader(statusCode int) {
-	lrw.status = statusCode
-	lrw.ResponseWriter.WriteHeader(statusCode)
+// newLogger expects a format already checked by ServerConfig.Validate.
+func newLogger(format string, level slog.Leveler) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: level}
+	if strings.EqualFold(format, "json") {
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts))
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, opts))
 }
 
-func loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		lrw := &loggingResponseWriter{ResponseWriter: w, status: http.StatusOK}
-		next.ServeHTTP(lrw, r)
-		duration := time.Since(start)
-		log.Printf("[http] %s %s -> %d (%s)", r.Method, r.URL.Path, lrw.status, duration)
-	})
+func fatal(logger *slog.Logger, msg string, err error) {
+	logger.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"chatgpt_go/internal/gateway"
+	"github.com/fsnotify/fsnotify"
+)
+
+const defaultServerConfigFile = "gateway.yaml"
+
+// bindServerFlags defines the flags that override gateway.yaml settings.
+func bindServerFlags(fs *flag.FlagSet, cfg *gateway.ServerConfig) {
+	fs.StringVar(&cfg.ServicesDir, "config", cfg.ServicesDir, "Directory containing MCP server definitions")
+	fs.StringVar(&cfg.StateFile, "state-file", cfg.StateFile, "JSON file persisting runtime enable/disable overrides (disabled when empty)")
+	fs.StringVar(&cfg.Listen.Addr, "addr", cfg.Listen.Addr, "Address for the gateway server (host:port or :port)")
+	fs.StringVar(&cfg.Listen.TLSCert, "tls-cert", cfg.Listen.TLSCert, "PEM certificate file for serving HTTPS")
+	fs.StringVar(&cfg.Listen.TLSKey, "tls-key", cfg.Listen.TLSKey, "PEM private key file for serving HTTPS")
+	fs.StringVar(&cfg.Listen.AdminAddr, "admin-addr", cfg.Listen.AdminAddr, "Address for the admin API listener (disabled when empty)")
+	fs.StringVar(&cfg.Auth.AdminToken, "admin-token", cfg.Auth.AdminToken, "Bearer token required by the admin API (generated when empty)")
+	fs.StringVar(&cfg.Logging.Format, "log-format", cfg.Logging.Format, "Log output format: text or json")
+	fs.StringVar(&cfg.Logging.Level, "log-level", cfg.Logging.Level, "Minimum log level: debug, info, warn or error")
+	fs.StringVar(&cfg.OpenAPI.ServerURL, "public-url", cfg.OpenAPI.ServerURL, "Public base URL advertised in the OpenAPI document (derived from requests when empty)")
+	fs.BoolVar(&cfg.OpenAPI.IncludeLoadErrors, "spec-load-errors", cfg.OpenAPI.IncludeLoadErrors, "List files that failed to load under x-gateway-load-errors in the OpenAPI document")
+	fs.Int64Var(&cfg.Services.RequestLimit.MaxBytes, "max-request-bytes", cfg.Services.RequestLimit.MaxBytes, "Default maximum request body size in bytes (0 disables the limit)")
+}
+
+func applyServerEnv(cfg *gateway.ServerConfig) {
+	setFromEnv(&cfg.ServicesDir, "CHATGPT_GATEWAY_CONFIG")
+	setFromEnv(&cfg.StateFile, "CHATGPT_GATEWAY_STATE_FILE")
+	if addr := os.Getenv("CHATGPT_GATEWAY_ADDR"); addr != "" {
+		cfg.Listen.Addr = addr
+	} else if port := os.Getenv("CHATGPT_GATEWAY_PORT"); port != "" {
+		if !strings.Contains(port, ":") {
+			port = ":" + port
+		}
+		cfg.Listen.Addr = port
+	}
+	setFromEnv(&cfg.Listen.TLSCert, "CHATGPT_GATEWAY_TLS_CERT")
+	setFromEnv(&cfg.Listen.TLSKey, "CHATGPT_GATEWAY_TLS_KEY")
+	setFromEnv(&cfg.Listen.AdminAddr, "CHATGPT_GATEWAY_ADMIN_ADDR")
+	setFromEnv(&cfg.Auth.AdminToken, "CHATGPT_GATEWAY_ADMIN_TOKEN")
+	if keys := os.Getenv("CHATGPT_GATEWAY_API_KEYS"); keys != "" {
+		cfg.Auth.APIKeys = nil
+		for _, key := range strings.Split(keys, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				cfg.Auth.APIKeys = append(cfg.Auth.APIKeys, key)
+			}
+		}
+	}
+	setFromEnv(&cfg.OpenAPI.ServerURL, "CHATGPT_GATEWAY_PUBLIC_URL")
+	if envBool("CHATGPT_GATEWAY_SPEC_LOAD_ERRORS") {
+		cfg.OpenAPI.IncludeLoadErrors = true
+	}
+	setFromEnv(&cfg.Logging.Format, "CHATGPT_GATEWAY_LOG_FORMAT")
+	setFromEnv(&cfg.Logging.Level, "CHATGPT_GATEWAY_LOG_LEVEL")
+}
+
+func setFromEnv(dst *string, key string) {
+	if value := os.Getenv(key); value != "" {
+		*dst = value
+	}
+}
+
+// serverConfigSource resolves the effective server configuration with the
+// precedence flags > environment > gateway.yaml > defaults. It is resolved
+// again on every reload so command line flags keep winning.
+type serverConfigSource struct {
+	path     string
+	explicit bool
+	flags    map[string]string
+}
+
+func newServerConfigSource(path string, fs *flag.FlagSet) *serverConfigSource {
+	s := &serverConfigSource{path: path, flags: make(map[string]string)}
+	fs.Visit(func(f *flag.Flag) {
+		s.flags[f.Name] = f.Value.String()
+	})
+	_, s.explicit = s.flags["gateway-config"]
+	if os.Getenv("CHATGPT_GATEWAY_CONFIG_FILE") != "" {
+		s.explicit = true
+	}
+	return s
+}
+
+func (s *serverConfigSource) resolve() (gateway.ServerConfig, error) {
+	cfg := gateway.DefaultServerConfig()
+	if err := gateway.LoadServerConfig(s.path, &cfg); err != nil && (s.explicit || !errors.Is(err, os.ErrNotExist)) {
+		return cfg, err
+	}
+	applyServerEnv(&cfg)
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	bindServerFlags(fs, &cfg)
+	for name, value := range s.flags {
+		if fs.Lookup(name) == nil {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return cfg, fmt.Errorf("-%s: %w", name, err)
+		}
+	}
+	return cfg, cfg.Validate()
+}
+
+// restartRequired lists the settings that differ between two configurations
+// but are only read at startup.
+func restartRequired(old, next gateway.ServerConfig) []string {
+	var changed []string
+	check := func(name string, differs bool) {
+		if differs {
+			changed = append(changed, name)
+		}
+	}
+	check("servicesDir", old.ServicesDir != next.ServicesDir)
+	check("stateFile", old.StateFile != next.StateFile)
+	check("listen", old.Listen != next.Listen)
+	check("timeouts", old.Timeouts != next.Timeouts)
+	check("auth.adminToken", old.Auth.AdminToken != next.Auth.AdminToken)
+	check("logging.format", !strings.EqualFold(old.Logging.Format, next.Logging.Format))
+	check("services.timeout", old.Services.Timeout != next.Services.Timeout)
+	return changed
+}
+
+// watchServerConfig calls reload whenever the file at path is written,
+// created or replaced. Editors often save by renaming, so the directory is
+// watched rather than the file.
+func watchServerConfig(ctx context.Context, logger *slog.Logger, path string, reload func()) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return err
+	}
+	var mu sync.Mutex
+	debounced := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		reload()
+	}
+	go func() {
+		defer watcher.Close()
+		var timer *time.Timer
+		for {
+			select {
+			case <-ctx.Done():
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != path || event.Op == fsnotify.Chmod {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(300*time.Millisecond, debounced)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logger.Warn("gateway config watcher error", "error", err)
+			}
+		}
+	}()
+	return nil
+}
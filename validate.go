@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"chatgpt_go/internal/gateway"
+)
+
+func runValidate(args []string) int {
+	fs := newCommandFlags("validate")
+	strict := fs.Bool("strict", false, "Treat warnings as errors")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	paths := fs.Args()
+	if len(paths) == 0 {
+		cfg, err := newServerConfigSource(envOrDefault("CHATGPT_GATEWAY_CONFIG_FILE", defaultServerConfigFile), fs).resolve()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "validate: %v\n", err)
+			return 2
+		}
+		paths = []string{cfg.ServicesDir}
+	}
+
+	diags, err := gateway.ValidateFiles(paths...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "validate: %v\n", err)
+		return 2
+	}
+	errorCount, warningCount := 0, 0
+	for _, d := range diags {
+		fmt.Println(d)
+		if d.Severity == gateway.SeverityError {
+			errorCount++
+		} else {
+			warningCount++
+		}
+	}
+	fmt.Fprintf(os.Stderr, "%d error(s), %d warning(s)\n", errorCount, warningCount)
+	if errorCount > 0 || (*strict && warningCount > 0) {
+		return 1
+	}
+	return 0
+}